@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/Gretamass/kys-backend/db"
+	"github.com/Gretamass/kys-backend/user"
+	"github.com/gin-gonic/gin/binding"
+	"io"
+	"os"
+	"strings"
+)
+
+const createAdminUsage = "usage: kys-backend create-admin <email> (password from ADMIN_PASSWORD or stdin)"
+
+// runCreateAdmin handles the create-admin subcommand. Creating admins through
+// the API needs an admin token, so this is how the first admin of a fresh
+// database is made. The password is read from ADMIN_PASSWORD or, when that is
+// unset, from the first line of stdin, so it never shows up in argv.
+func runCreateAdmin(path string, args []string, stdin io.Reader) error {
+	if len(args) != 1 {
+		return errors.New(createAdminUsage)
+	}
+
+	password, ok := os.LookupEnv("ADMIN_PASSWORD")
+	if !ok {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	if err := registerValidations(); err != nil {
+		return err
+	}
+
+	admin := user.Admin{Email: args[0], Password: password}
+	if err := binding.Validator.ValidateStruct(admin); err != nil {
+		return fmt.Errorf("invalid admin: %w", err)
+	}
+
+	dbc, err := db.ConnectDatabase(path)
+	if err != nil {
+		return err
+	}
+
+	defer dbc.Close()
+
+	if err := dbc.AddAdmin(admin); err != nil {
+		return err
+	}
+
+	fmt.Printf("created admin %s\n", admin.Email)
+	return nil
+}
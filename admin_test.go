@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/Gretamass/kys-backend/db"
+	"github.com/Gretamass/kys-backend/user"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateAdminOnFreshDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kys.db")
+	t.Setenv("ADMIN_PASSWORD", "bootstrap1")
+
+	if err := runCreateAdmin(path, []string{"root@kys.test"}, strings.NewReader("")); err != nil {
+		t.Fatalf("create-admin: %v", err)
+	}
+
+	if err := runCreateAdmin(path, []string{"second@kys.test"}, nil); err != nil {
+		t.Fatalf("create second admin: %v", err)
+	}
+
+	t.Setenv("ADMIN_PASSWORD", "short")
+	if err := runCreateAdmin(path, []string{"other@kys.test"}, nil); err == nil {
+		t.Error("create-admin accepted a weak password")
+	}
+
+	dbc, err := db.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer dbc.Close()
+
+	if _, ok, err := dbc.LoginAdmin(user.Admin{Email: "root@kys.test", Password: "bootstrap1"}); err != nil || !ok {
+		t.Fatalf("login as created admin: ok %v, err %v", ok, err)
+	}
+}
@@ -0,0 +1,62 @@
+package alert
+
+import "github.com/Gretamass/kys-backend/sneaker"
+
+// PriceAlert asks for a notification when a sneaker becomes available at or
+// below TargetPrice. A zero ProviderId watches every provider and a zero
+// TargetPrice fires on any restock.
+type PriceAlert struct {
+	Id          int     `json:"id"`
+	UserId      int     `json:"userId"`
+	SneakerId   int     `json:"sneakerId" binding:"required,gt=0"`
+	ProviderId  int     `json:"providerId,omitempty" binding:"gte=0"`
+	TargetPrice float32 `json:"targetPrice" binding:"gte=0"`
+	CreatedAt   string  `json:"createdAt,omitempty"`
+}
+
+// PriceAlertUpdate is a partial update of a PriceAlert. Fields left out of
+// the request stay as they are, so TargetPrice can be set to 0 and a
+// ProviderId of 0 makes the alert watch every provider again. SneakerId is
+// only decoded to reject it: an alert cannot move to another sneaker.
+type PriceAlertUpdate struct {
+	SneakerId   *int     `json:"sneakerId"`
+	ProviderId  *int     `json:"providerId" binding:"omitempty,gte=0"`
+	TargetPrice *float32 `json:"targetPrice" binding:"omitempty,gte=0"`
+}
+
+// TriggeredAlert records one crossing of a price alert.
+type TriggeredAlert struct {
+	Id          int     `json:"id"`
+	AlertId     int     `json:"alertId"`
+	UserId      int     `json:"userId"`
+	SneakerId   int     `json:"sneakerId"`
+	ProviderId  int     `json:"providerId"`
+	Price       float32 `json:"price"`
+	Available   bool    `json:"available"`
+	TriggeredAt string  `json:"triggeredAt,omitempty"`
+}
+
+// Matches reports whether availability satisfies the alert.
+func (a PriceAlert) Matches(availability sneaker.Availability) bool {
+	if a.ProviderId != 0 && a.ProviderId != availability.ProviderId {
+		return false
+	}
+
+	if !availability.Available {
+		return false
+	}
+
+	return a.TargetPrice == 0 || availability.Price <= a.TargetPrice
+}
+
+// Crossed reports whether the change from previous to current makes the
+// alert fire. Alerts fire once per crossing: staying below the target does
+// not fire again until the price has gone back above it or the sneaker was
+// out of stock. A nil previous means the product had no availability row yet.
+func (a PriceAlert) Crossed(previous *sneaker.Availability, current sneaker.Availability) bool {
+	if !a.Matches(current) {
+		return false
+	}
+
+	return previous == nil || !a.Matches(*previous)
+}
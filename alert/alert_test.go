@@ -0,0 +1,40 @@
+package alert
+
+import (
+	"github.com/Gretamass/kys-backend/sneaker"
+	"testing"
+)
+
+func TestCrossed(t *testing.T) {
+	at := func(price float32, available bool) *sneaker.Availability {
+		return &sneaker.Availability{ProviderId: 1, Price: price, Available: available}
+	}
+
+	tests := []struct {
+		name     string
+		alert    PriceAlert
+		previous *sneaker.Availability
+		current  *sneaker.Availability
+		want     bool
+	}{
+		{"first row below target", PriceAlert{TargetPrice: 100}, nil, at(90, true), true},
+		{"first row above target", PriceAlert{TargetPrice: 100}, nil, at(110, true), false},
+		{"drops below target", PriceAlert{TargetPrice: 100}, at(110, true), at(90, true), true},
+		{"reaches target exactly", PriceAlert{TargetPrice: 100}, at(110, true), at(100, true), true},
+		{"stays below target", PriceAlert{TargetPrice: 100}, at(95, true), at(90, true), false},
+		{"stays above target", PriceAlert{TargetPrice: 100}, at(120, true), at(110, true), false},
+		{"rises above target", PriceAlert{TargetPrice: 100}, at(90, true), at(110, true), false},
+		{"restocks below target", PriceAlert{TargetPrice: 100}, at(90, false), at(90, true), true},
+		{"sells out below target", PriceAlert{TargetPrice: 100}, at(90, true), at(90, false), false},
+		{"any restock", PriceAlert{}, at(300, false), at(300, true), true},
+		{"any restock while in stock", PriceAlert{}, at(300, true), at(250, true), false},
+		{"other provider", PriceAlert{ProviderId: 2, TargetPrice: 100}, at(110, true), at(90, true), false},
+		{"watched provider", PriceAlert{ProviderId: 1, TargetPrice: 100}, at(110, true), at(90, true), true},
+	}
+
+	for _, test := range tests {
+		if got := test.alert.Crossed(test.previous, *test.current); got != test.want {
+			t.Errorf("%s: Crossed = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Roles carried in the role claim.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Claims identifies the subject of a token. UserId holds the admin id when
+// Role is RoleAdmin.
+type Claims struct {
+	UserId int    `json:"user_id"`
+	Role   string `json:"role"`
+	jwt.StandardClaims
+}
+
+// TokenManager issues and verifies HS256 signed access tokens and generates
+// refresh tokens.
+type TokenManager struct {
+	secret     []byte
+	issuer     string
+	ttl        time.Duration
+	refreshTTL time.Duration
+}
+
+func NewTokenManager(secret string, issuer string, ttl time.Duration, refreshTTL time.Duration) *TokenManager {
+	return &TokenManager{
+		secret:     []byte(secret),
+		issuer:     issuer,
+		ttl:        ttl,
+		refreshTTL: refreshTTL,
+	}
+}
+
+func (m *TokenManager) Issue(userId int, role string) (string, error) {
+	now := time.Now()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		UserId: userId,
+		Role:   role,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: now.Add(m.ttl).Unix(),
+			IssuedAt:  now.Unix(),
+			Issuer:    m.issuer,
+		},
+	})
+
+	return token.SignedString(m.secret)
+}
+
+// Parse verifies the signature of tokenString and requires the exp, iat and
+// iss claims to be present and valid.
+func (m *TokenManager) Parse(tokenString string) (*Claims, error) {
+	parser := jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg()}}
+
+	claims := &Claims{}
+	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	now := time.Now().Unix()
+
+	if !claims.VerifyExpiresAt(now, true) {
+		return nil, fmt.Errorf("%w: missing or expired exp claim", ErrInvalidToken)
+	}
+
+	if !claims.VerifyIssuedAt(now, true) {
+		return nil, fmt.Errorf("%w: missing or future iat claim", ErrInvalidToken)
+	}
+
+	if !claims.VerifyIssuer(m.issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected iss claim", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
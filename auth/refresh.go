@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// RefreshToken is the stored form of a refresh token. Only the hash of the
+// token handed to the client is kept. Rotated tokens share the FamilyId of
+// the login they descend from.
+type RefreshToken struct {
+	Id        int
+	Hash      string
+	FamilyId  string
+	SubjectId int
+	Role      string
+	ExpiresAt time.Time
+}
+
+// NewRefreshToken generates a refresh token for the subject. An empty
+// familyId starts a new family.
+func (m *TokenManager) NewRefreshToken(subjectId int, role string, familyId string) (string, RefreshToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", RefreshToken{}, err
+	}
+
+	if familyId == "" {
+		family := make([]byte, 16)
+		if _, err := rand.Read(family); err != nil {
+			return "", RefreshToken{}, err
+		}
+		familyId = hex.EncodeToString(family)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	return token, RefreshToken{
+		Hash:      HashRefreshToken(token),
+		FamilyId:  familyId,
+		SubjectId: subjectId,
+		Role:      role,
+		ExpiresAt: time.Now().Add(m.refreshTTL),
+	}, nil
+}
+
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
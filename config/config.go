@@ -0,0 +1,357 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the server configuration. It is read from a YAML file and
+// environment variables override single values.
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Scraper  ScraperConfig  `yaml:"scraper"`
+	Notify   NotifyConfig   `yaml:"notify"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+type ServerConfig struct {
+	Addr           string   `yaml:"addr"`
+	TrustedProxies []string `yaml:"trustedProxies"`
+	// CORSOrigins lists the allowed origins. "*" allows every origin.
+	CORSOrigins []string `yaml:"corsOrigins"`
+
+	ReadTimeout       Duration `yaml:"readTimeout"`
+	ReadHeaderTimeout Duration `yaml:"readHeaderTimeout"`
+	WriteTimeout      Duration `yaml:"writeTimeout"`
+	IdleTimeout       Duration `yaml:"idleTimeout"`
+	// ShutdownTimeout bounds how long in-flight requests may take to
+	// finish once a shutdown signal arrives.
+	ShutdownTimeout Duration `yaml:"shutdownTimeout"`
+	MaxHeaderBytes  int      `yaml:"maxHeaderBytes"`
+	// MetricsPath serves the Prometheus metrics. Empty disables them.
+	MetricsPath string `yaml:"metricsPath"`
+
+	// TLSCertFile and TLSKeyFile enable HTTPS when both are set.
+	TLSCertFile string `yaml:"tlsCertFile"`
+	TLSKeyFile  string `yaml:"tlsKeyFile"`
+}
+
+type DatabaseConfig struct {
+	Path string `yaml:"path"`
+}
+
+type AuthConfig struct {
+	JWTSecret       string   `yaml:"jwtSecret"`
+	TokenTTL        Duration `yaml:"tokenTtl"`
+	RefreshTokenTTL Duration `yaml:"refreshTokenTtl"`
+}
+
+type ScraperConfig struct {
+	// Adapters is the path of the JSON adapter list. The scheduler only
+	// runs when it is set.
+	Adapters string   `yaml:"adapters"`
+	Interval Duration `yaml:"interval"`
+	// Concurrency is the number of scrappers run at once per provider and
+	// ProviderConcurrency overrides it by provider id.
+	Concurrency         int         `yaml:"concurrency"`
+	ProviderConcurrency map[int]int `yaml:"providerConcurrency"`
+	BaseBackoff         Duration    `yaml:"baseBackoff"`
+	MaxBackoff          Duration    `yaml:"maxBackoff"`
+}
+
+type NotifyConfig struct {
+	SMTP       SMTPConfig `yaml:"smtp"`
+	WebhookURL string     `yaml:"webhookUrl"`
+}
+
+type SMTPConfig struct {
+	Addr     string `yaml:"addr"`
+	From     string `yaml:"from"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type LogConfig struct {
+	// Level is one of debug, info, warn and error.
+	Level string `yaml:"level"`
+	// Format is json or text.
+	Format string `yaml:"format"`
+}
+
+// Duration reads durations such as "15m" from YAML.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// Default returns the configuration used for everything the file and
+// environment leave unset.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:        ":8080",
+			CORSOrigins: []string{"*"},
+
+			ReadTimeout:       Duration(15 * time.Second),
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			// the manual scrapper run endpoint waits for provider pages
+			WriteTimeout:    Duration(2 * time.Minute),
+			IdleTimeout:     Duration(time.Minute),
+			ShutdownTimeout: Duration(10 * time.Second),
+			MaxHeaderBytes:  1 << 20,
+			MetricsPath:     "/metrics",
+		},
+		Database: DatabaseConfig{
+			Path: "./sqlite.db",
+		},
+		Auth: AuthConfig{
+			TokenTTL:        Duration(15 * time.Minute),
+			RefreshTokenTTL: Duration(30 * 24 * time.Hour),
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Scraper: ScraperConfig{
+			Interval:    Duration(time.Hour),
+			Concurrency: 2,
+			BaseBackoff: Duration(time.Minute),
+			MaxBackoff:  Duration(6 * time.Hour),
+		},
+	}
+}
+
+// Load reads the defaults, then the YAML file at path, then the environment.
+// A missing file is only an error when required is set. Load does not
+// validate the result.
+func Load(path string, required bool) (*Config, error) {
+	c := Default()
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.UnmarshalStrict(data, c); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && !required:
+	default:
+		return nil, err
+	}
+
+	if err := c.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// applyEnv overrides values with the environment variables that are set.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	text := map[string]*string{
+		"LISTEN_ADDR":    &c.Server.Addr,
+		"DB_PATH":        &c.Database.Path,
+		"JWT_SECRET":     &c.Auth.JWTSecret,
+		"SCRAPER_CONFIG": &c.Scraper.Adapters,
+		"TLS_CERT_FILE":  &c.Server.TLSCertFile,
+		"TLS_KEY_FILE":   &c.Server.TLSKeyFile,
+		"METRICS_PATH":   &c.Server.MetricsPath,
+		"SMTP_ADDR":      &c.Notify.SMTP.Addr,
+		"SMTP_FROM":      &c.Notify.SMTP.From,
+		"SMTP_USERNAME":  &c.Notify.SMTP.Username,
+		"SMTP_PASSWORD":  &c.Notify.SMTP.Password,
+		"WEBHOOK_URL":    &c.Notify.WebhookURL,
+		"LOG_LEVEL":      &c.Log.Level,
+		"LOG_FORMAT":     &c.Log.Format,
+	}
+
+	for name, target := range text {
+		if value, ok := lookup(name); ok {
+			*target = value
+		}
+	}
+
+	// PORT predates LISTEN_ADDR and only sets the port
+	if port, ok := lookup("PORT"); ok {
+		if _, set := lookup("LISTEN_ADDR"); !set {
+			c.Server.Addr = ":" + port
+		}
+	}
+
+	lists := map[string]*[]string{
+		"TRUSTED_PROXIES": &c.Server.TrustedProxies,
+		"CORS_ORIGINS":    &c.Server.CORSOrigins,
+	}
+
+	for name, target := range lists {
+		if value, ok := lookup(name); ok {
+			*target = splitList(value)
+		}
+	}
+
+	durations := map[string]*Duration{
+		"JWT_TTL":           &c.Auth.TokenTTL,
+		"REFRESH_TOKEN_TTL": &c.Auth.RefreshTokenTTL,
+		"SCRAPER_INTERVAL":  &c.Scraper.Interval,
+	}
+
+	for name, target := range durations {
+		if value, ok := lookup(name); ok {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*target = Duration(parsed)
+		}
+	}
+
+	if value, ok := lookup("SCRAPER_CONCURRENCY"); ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("SCRAPER_CONCURRENCY: %w", err)
+		}
+		c.Scraper.Concurrency = parsed
+	}
+
+	return nil
+}
+
+func splitList(value string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var problems []string
+
+	if c.Server.Addr == "" {
+		problems = append(problems, "server.addr is required")
+	}
+
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problems = append(problems, fmt.Sprintf("server.trustedProxies: %q is not an IP or CIDR", proxy))
+			}
+		}
+	}
+
+	for _, origin := range c.Server.CORSOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			problems = append(problems, fmt.Sprintf("server.corsOrigins: %q must be * or start with http:// or https://", origin))
+		}
+	}
+
+	if path := c.Server.MetricsPath; path != "" && !strings.HasPrefix(path, "/") {
+		problems = append(problems, fmt.Sprintf("server.metricsPath: %q must start with /", path))
+	}
+
+	timeouts := []struct {
+		name  string
+		value Duration
+	}{
+		{"server.readTimeout", c.Server.ReadTimeout},
+		{"server.readHeaderTimeout", c.Server.ReadHeaderTimeout},
+		{"server.writeTimeout", c.Server.WriteTimeout},
+		{"server.idleTimeout", c.Server.IdleTimeout},
+		{"server.shutdownTimeout", c.Server.ShutdownTimeout},
+	}
+
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			problems = append(problems, timeout.name+" must be positive")
+		}
+	}
+
+	if c.Server.MaxHeaderBytes <= 0 {
+		problems = append(problems, "server.maxHeaderBytes must be positive")
+	}
+
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		problems = append(problems, "server.tlsCertFile and server.tlsKeyFile must be set together")
+	}
+
+	if c.Database.Path == "" {
+		problems = append(problems, "database.path is required")
+	}
+
+	if c.Auth.JWTSecret == "" {
+		problems = append(problems, "auth.jwtSecret is required")
+	}
+
+	if c.Auth.TokenTTL <= 0 {
+		problems = append(problems, "auth.tokenTtl must be positive")
+	}
+
+	if c.Auth.RefreshTokenTTL <= c.Auth.TokenTTL {
+		problems = append(problems, "auth.refreshTokenTtl must be longer than auth.tokenTtl")
+	}
+
+	if c.Scraper.Interval <= 0 {
+		problems = append(problems, "scraper.interval must be positive")
+	}
+
+	if c.Scraper.Concurrency < 1 {
+		problems = append(problems, "scraper.concurrency must be at least 1")
+	}
+
+	for providerId, concurrency := range c.Scraper.ProviderConcurrency {
+		if concurrency < 1 {
+			problems = append(problems, fmt.Sprintf("scraper.providerConcurrency: provider %d must be at least 1", providerId))
+		}
+	}
+
+	if c.Scraper.BaseBackoff <= 0 || c.Scraper.MaxBackoff < c.Scraper.BaseBackoff {
+		problems = append(problems, "scraper.baseBackoff must be positive and at most scraper.maxBackoff")
+	}
+
+	if c.Notify.SMTP.Addr != "" && c.Notify.SMTP.From == "" {
+		problems = append(problems, "notify.smtp.from is required when notify.smtp.addr is set")
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		problems = append(problems, fmt.Sprintf("log.level: %q is not debug, info, warn or error", c.Log.Level))
+	}
+
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		problems = append(problems, fmt.Sprintf("log.format: %q is not json or text", c.Log.Format))
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid config: " + strings.Join(problems, "; "))
+	}
+
+	return nil
+}
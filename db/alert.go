@@ -0,0 +1,343 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/Gretamass/kys-backend/alert"
+	"github.com/Gretamass/kys-backend/sneaker"
+	"strings"
+)
+
+// ErrAlertSneakerChanged is returned when an alert update sets sneakerId. It
+// is an ErrValidation of that field.
+var ErrAlertSneakerChanged = &Error{Kind: ErrValidation, Message: "the sneaker of an alert cannot be changed, create a new alert instead", Field: "sneakerId"}
+
+func (d *DB) GetPriceAlerts(userId int) ([]alert.PriceAlert, error) {
+	rows, err := d.db.Query("SELECT id, user_id, sneaker_id, provider_id, target_price, created_at FROM price_alerts WHERE user_id = ?", userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	alerts := make([]alert.PriceAlert, 0)
+
+	for rows.Next() {
+		singleAlert, err := scanPriceAlert(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		alerts = append(alerts, singleAlert)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+func (d *DB) GetPriceAlertById(userId int, alertId int) (alert.PriceAlert, error) {
+	row := d.db.QueryRow("SELECT id, user_id, sneaker_id, provider_id, target_price, created_at FROM price_alerts WHERE id = ? AND user_id = ?", alertId, userId)
+
+	singleAlert, err := scanPriceAlert(row)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return alert.PriceAlert{}, NotFound("alert", alertId)
+		}
+		return alert.PriceAlert{}, err
+	}
+
+	return singleAlert, nil
+}
+
+// AddPriceAlert stores an alert for an existing user. The sneaker and the
+// provider, when set, must exist as well.
+func (d *DB) AddPriceAlert(newAlert alert.PriceAlert) (int, error) {
+	exists, err := d.rowExists("users", newAlert.UserId)
+	if err != nil {
+		return 0, err
+	}
+
+	if !exists {
+		return 0, NotFound("user", newAlert.UserId)
+	}
+
+	if err := d.checkAlertReferences(newAlert); err != nil {
+		return 0, err
+	}
+
+	row, err := d.db.Prepare("INSERT INTO price_alerts (user_id, sneaker_id, provider_id, target_price) VALUES (?, ?, ?, ?)")
+
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := row.Exec(newAlert.UserId, newAlert.SneakerId, nullableId(newAlert.ProviderId), newAlert.TargetPrice)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdatePriceAlert applies the fields set in request to an alert of the
+// user.
+func (d *DB) UpdatePriceAlert(userId int, alertId int, request alert.PriceAlertUpdate) error {
+	if request.SneakerId != nil {
+		return ErrAlertSneakerChanged
+	}
+
+	query := "UPDATE price_alerts SET "
+	var args []interface{}
+
+	if request.ProviderId != nil {
+		if err := d.checkAlertReferences(alert.PriceAlert{ProviderId: *request.ProviderId}); err != nil {
+			return err
+		}
+
+		query += "provider_id = ?, "
+		args = append(args, nullableId(*request.ProviderId))
+	}
+
+	if request.TargetPrice != nil {
+		query += "target_price = ?, "
+		args = append(args, *request.TargetPrice)
+	}
+
+	if len(args) == 0 {
+		return Invalid("no fields to update for alert with id %d", alertId)
+	}
+
+	query = strings.TrimRight(query, ", ")
+	query += " WHERE id = ? AND user_id = ?"
+	args = append(args, alertId, userId)
+
+	row, err := d.db.Prepare(query)
+
+	if err != nil {
+		return err
+	}
+
+	result, err := row.Exec(args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return NotFound("alert", alertId)
+	}
+
+	return nil
+}
+
+// checkAlertReferences rejects alerts on sneakers or providers that do not
+// exist. Zero ids are not checked.
+func (d *DB) checkAlertReferences(priceAlert alert.PriceAlert) error {
+	references := []struct {
+		field    string
+		resource string
+		table    string
+		id       int
+	}{
+		{"sneakerId", "sneaker", "sneakers", priceAlert.SneakerId},
+		{"providerId", "provider", "product_providers", priceAlert.ProviderId},
+	}
+
+	for _, reference := range references {
+		if reference.id == 0 {
+			continue
+		}
+
+		exists, err := d.rowExists(reference.table, reference.id)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return UnknownReference(reference.field, reference.resource, reference.id)
+		}
+	}
+
+	return nil
+}
+
+func (d *DB) rowExists(table string, id int) (bool, error) {
+	var exists bool
+	err := d.db.QueryRow("SELECT EXISTS(SELECT 1 FROM "+table+" WHERE id = ?)", id).Scan(&exists)
+	return exists, err
+}
+
+func (d *DB) DeletePriceAlert(userId int, alertId int) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM triggered_alerts WHERE alert_id IN (SELECT id FROM price_alerts WHERE id = ? AND user_id = ?)", alertId, userId); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM price_alerts WHERE id = ? AND user_id = ?", alertId, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return NotFound("alert", alertId)
+	}
+
+	return tx.Commit()
+}
+
+func (d *DB) GetTriggeredAlerts(userId int) ([]alert.TriggeredAlert, error) {
+	query := `
+        SELECT ta.id, ta.alert_id, pa.user_id, pa.sneaker_id, ta.provider_id, ta.price, ta.available, ta.triggered_at
+        FROM triggered_alerts ta
+        JOIN price_alerts pa ON pa.id = ta.alert_id
+        WHERE pa.user_id = ?
+        ORDER BY ta.triggered_at DESC, ta.id DESC;
+    `
+	rows, err := d.db.Query(query, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	triggered := make([]alert.TriggeredAlert, 0)
+
+	for rows.Next() {
+		single := alert.TriggeredAlert{}
+		err = rows.Scan(&single.Id, &single.AlertId, &single.UserId, &single.SneakerId, &single.ProviderId,
+			&single.Price, &single.Available, &single.TriggeredAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		triggered = append(triggered, single)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return triggered, nil
+}
+
+// evaluatePriceAlerts records a trigger for every alert on the product that
+// is crossed by the change from previous to current and queues the
+// notifications about it.
+func (d *DB) evaluatePriceAlerts(tx *sql.Tx, previous *sneaker.Availability, current sneaker.Availability) error {
+	query := `
+        SELECT id, user_id, sneaker_id, provider_id, target_price, created_at
+        FROM price_alerts
+        WHERE sneaker_id = ? AND (provider_id IS NULL OR provider_id = ?);
+    `
+	rows, err := tx.Query(query, current.ProductId, current.ProviderId)
+
+	if err != nil {
+		return err
+	}
+
+	var crossed []alert.PriceAlert
+
+	for rows.Next() {
+		singleAlert, err := scanPriceAlert(rows)
+
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		if singleAlert.Crossed(previous, current) {
+			crossed = append(crossed, singleAlert)
+		}
+	}
+
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for _, singleAlert := range crossed {
+		result, err := tx.Exec("INSERT INTO triggered_alerts (alert_id, provider_id, price, available) VALUES (?, ?, ?, ?)",
+			singleAlert.Id, current.ProviderId, current.Price, current.Available)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		triggered := alert.TriggeredAlert{
+			Id:         int(id),
+			AlertId:    singleAlert.Id,
+			UserId:     singleAlert.UserId,
+			SneakerId:  singleAlert.SneakerId,
+			ProviderId: current.ProviderId,
+			Price:      current.Price,
+			Available:  current.Available,
+		}
+
+		if err := d.enqueueAlertNotifications(tx, triggered); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPriceAlert(row scanner) (alert.PriceAlert, error) {
+	singleAlert := alert.PriceAlert{}
+	var providerId sql.NullInt64
+
+	err := row.Scan(&singleAlert.Id, &singleAlert.UserId, &singleAlert.SneakerId, &providerId, &singleAlert.TargetPrice, &singleAlert.CreatedAt)
+	if err != nil {
+		return alert.PriceAlert{}, err
+	}
+
+	singleAlert.ProviderId = int(providerId.Int64)
+
+	return singleAlert, nil
+}
+
+// nullableId stores a zero id as NULL.
+func nullableId(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+
+	return id
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"github.com/Gretamass/kys-backend/alert"
+	"github.com/Gretamass/kys-backend/provider"
+	"github.com/Gretamass/kys-backend/sneaker"
+	"github.com/Gretamass/kys-backend/user"
+	"testing"
+)
+
+func TestPriceAlertsTriggerOncePerCrossing(t *testing.T) {
+	d := openTestDB(t)
+
+	if err := d.AddUser(user.User{Email: "greta@test.com", Password: "sneakers1"}); err != nil {
+		t.Fatalf("add user: %v", err)
+	}
+
+	users, _, err := d.GetUsers(ListOptions{})
+	if err != nil {
+		t.Fatalf("get users: %v", err)
+	}
+	userId := users[0].Id
+
+	sneakerId, err := d.AddSneaker(sneaker.Sneaker{Name: "Dunk Low", Model: "Panda", Brand: "Nike"})
+	if err != nil {
+		t.Fatalf("add sneaker: %v", err)
+	}
+
+	providerId, err := d.AddProvider(provider.ProviderInformation{ProviderName: "Shop"})
+	if err != nil {
+		t.Fatalf("add provider: %v", err)
+	}
+
+	if _, err := d.AddPriceAlert(alert.PriceAlert{UserId: userId, SneakerId: sneakerId, TargetPrice: 100}); err != nil {
+		t.Fatalf("add alert: %v", err)
+	}
+
+	steps := []struct {
+		name      string
+		price     float32
+		available bool
+		triggered int
+	}{
+		{"first row above target", 110, true, 0},
+		{"drops below target", 90, true, 1},
+		{"stays below target", 85, true, 1},
+		{"unchanged", 85, true, 1},
+		{"rises but stays below target", 95, true, 1},
+		{"rises above target", 120, true, 1},
+		{"stays above target", 115, true, 1},
+		{"drops below target again", 90, true, 2},
+		{"sells out", 90, false, 2},
+		{"restocks below target", 90, true, 3},
+	}
+
+	for _, step := range steps {
+		err := d.UpsertProviderInformation(sneaker.Availability{ProductId: sneakerId, ProviderId: providerId, Price: step.price, Available: step.available})
+		if err != nil {
+			t.Fatalf("%s: upsert: %v", step.name, err)
+		}
+
+		triggered, err := d.GetTriggeredAlerts(userId)
+		if err != nil {
+			t.Fatalf("%s: triggered alerts: %v", step.name, err)
+		}
+
+		if len(triggered) != step.triggered {
+			t.Fatalf("%s: %d triggers, want %d", step.name, len(triggered), step.triggered)
+		}
+	}
+}
+
+func TestUpdatePriceAlertSetsZeroValues(t *testing.T) {
+	d := openTestDB(t)
+
+	if err := d.AddUser(user.User{Email: "greta@test.com", Password: "sneakers1"}); err != nil {
+		t.Fatalf("add user: %v", err)
+	}
+
+	sneakerId, _ := d.AddSneaker(sneaker.Sneaker{Name: "Dunk Low", Model: "Panda", Brand: "Nike"})
+	providerId, _ := d.AddProvider(provider.ProviderInformation{ProviderName: "Shop"})
+
+	alertId, err := d.AddPriceAlert(alert.PriceAlert{UserId: 1, SneakerId: sneakerId, ProviderId: providerId, TargetPrice: 100})
+	if err != nil {
+		t.Fatalf("add alert: %v", err)
+	}
+
+	zeroId, zeroPrice := 0, float32(0)
+	if err := d.UpdatePriceAlert(1, alertId, alert.PriceAlertUpdate{ProviderId: &zeroId, TargetPrice: &zeroPrice}); err != nil {
+		t.Fatalf("update alert: %v", err)
+	}
+
+	updated, err := d.GetPriceAlertById(1, alertId)
+	if err != nil {
+		t.Fatalf("get alert: %v", err)
+	}
+
+	if updated.ProviderId != 0 || updated.TargetPrice != 0 {
+		t.Errorf("alert = %+v, want no provider and target price 0", updated)
+	}
+
+	err = d.UpdatePriceAlert(1, alertId, alert.PriceAlertUpdate{SneakerId: &sneakerId})
+	if err != ErrAlertSneakerChanged {
+		t.Errorf("moving the alert: err = %v, want ErrAlertSneakerChanged", err)
+	}
+}
@@ -2,20 +2,62 @@ package db
 
 import (
 	"database/sql"
-	"fmt"
+	"errors"
+	"github.com/Gretamass/kys-backend/auth"
 	"github.com/Gretamass/kys-backend/provider"
 	"github.com/Gretamass/kys-backend/sneaker"
 	"github.com/Gretamass/kys-backend/user"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
 	_ "modernc.org/sqlite"
 	"strings"
+	"time"
 )
 
+// sqliteTimeFormat matches the text stored by CURRENT_TIMESTAMP defaults.
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// ErrProviderInUse is returned when deleting a provider that is still
+// referenced by availability, scrapper, price history or alert rows. It is an
+// ErrConflict.
+var ErrProviderInUse = Conflict("provider is still referenced by availability, scrapper, price history or alert rows")
+
 type DB struct {
 	db *sql.DB
+
+	notificationChannels []string
+}
+
+// ConnectDatabase opens the database at path, creating it if needed, and
+// applies any pending migrations.
+func ConnectDatabase(path string) (*DB, error) {
+	d, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := d.MigrateUp()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(applied) > 0 {
+		slog.Info("applied migrations", "versions", applied)
+	}
+
+	return d, nil
 }
 
-func ConnectDatabase() (*DB, error) {
-	db, err := sql.Open("sqlite", "./sqlite.db")
+// connectionParams are applied to every pooled connection. The scheduler,
+// the dispatcher and HTTP handlers write concurrently, so connections wait
+// for a busy database instead of failing with SQLITE_BUSY, readers do not
+// block the writer (WAL), and transactions take the write lock when they
+// begin rather than on their first write, which SQLite cannot wait for.
+const connectionParams = "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+// Open opens the database at path without running migrations.
+func Open(path string) (*DB, error) {
+	db, err := sql.Open("sqlite", path+"?"+connectionParams)
 	if err != nil {
 		return nil, err
 	}
@@ -29,13 +71,37 @@ func ConnectDatabase() (*DB, error) {
 	}, nil
 }
 
+// Close closes the database. It must only be called once nothing uses the
+// database any more.
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// Stats reports the connection pool statistics.
+func (d *DB) Stats() sql.DBStats {
+	return d.db.Stats()
+}
+
 // USER methods
 
-func (d *DB) GetUsers() ([]user.User, error) {
-	rows, err := d.db.Query("SELECT * FROM users")
+var userSortColumns = map[string]string{
+	"id":        "id",
+	"email":     "email",
+	"createdAt": "created_at",
+}
+
+// GetUsers returns a page of users together with the total number of users
+// matching the email filter.
+func (d *DB) GetUsers(opts ListOptions) ([]user.User, int, error) {
+	q := &listQuery{}
+	if email := opts.Filters["email"]; email != "" {
+		q.filter("email LIKE ?", "%"+email+"%")
+	}
+
+	rows, total, err := d.list("id, email, created_at", "users", q, opts, userSortColumns, "id")
 
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -44,10 +110,10 @@ func (d *DB) GetUsers() ([]user.User, error) {
 
 	for rows.Next() {
 		singleUser := user.User{}
-		err = rows.Scan(&singleUser.Id, &singleUser.Email, &singleUser.Password, &singleUser.CreatedAt)
+		err = rows.Scan(&singleUser.Id, &singleUser.Email, &singleUser.CreatedAt)
 
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		users = append(users, singleUser)
@@ -56,21 +122,21 @@ func (d *DB) GetUsers() ([]user.User, error) {
 	err = rows.Err()
 
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return users, nil
+	return users, total, nil
 }
 
 func (d *DB) GetUserById(userId int) (user.User, error) {
-	row := d.db.QueryRow("SELECT * FROM users WHERE id = ?", userId)
+	row := d.db.QueryRow("SELECT id, email, created_at FROM users WHERE id = ?", userId)
 
 	singleUser := user.User{}
-	err := row.Scan(&singleUser.Id, &singleUser.Email, &singleUser.Password, &singleUser.CreatedAt)
+	err := row.Scan(&singleUser.Id, &singleUser.Email, &singleUser.CreatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return user.User{}, fmt.Errorf("no rows found with id %d", userId)
+			return user.User{}, NotFound("user", userId)
 		}
 		return user.User{}, err
 	}
@@ -78,15 +144,23 @@ func (d *DB) GetUserById(userId int) (user.User, error) {
 	return singleUser, nil
 }
 
-func (d *DB) AddUser(user user.User) error {
+func (d *DB) AddUser(newUser user.User) error {
+	hash, err := HashPassword(newUser.Password)
+	if err != nil {
+		return err
+	}
+
 	row, err := d.db.Prepare("INSERT INTO users (email, password) VALUES (?, ?)")
 
 	if err != nil {
 		return err
 	}
 
-	_, err = row.Exec(user.Email, user.Password)
+	_, err = row.Exec(newUser.Email, hash)
 	if err != nil {
+		if uniqueViolation(err) {
+			return EmailTaken(newUser.Email)
+		}
 		return err
 	}
 
@@ -103,8 +177,17 @@ func (d *DB) UpdateUser(userId int, request user.User) error {
 	}
 
 	if request.Password != "" {
+		hash, err := HashPassword(request.Password)
+		if err != nil {
+			return err
+		}
+
 		query += "password = ?, "
-		args = append(args, request.Password)
+		args = append(args, hash)
+	}
+
+	if len(args) == 0 {
+		return Invalid("no fields to update for user with id %d", userId)
 	}
 
 	query = strings.TrimRight(query, ", ")
@@ -117,23 +200,54 @@ func (d *DB) UpdateUser(userId int, request user.User) error {
 		return err
 	}
 
-	_, err = row.Exec(args...)
+	result, err := row.Exec(args...)
+	if err != nil {
+		if uniqueViolation(err) {
+			return EmailTaken(request.Email)
+		}
+		return err
+	}
 
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 
+	if rowsAffected == 0 {
+		return NotFound("user", userId)
+	}
+
 	return nil
 }
 
+// DeleteUser removes the user together with their price alerts and
+// notifications and revokes their refresh tokens.
 func (d *DB) DeleteUser(userId int) error {
-	row, err := d.db.Prepare("DELETE FROM users WHERE id = ?")
-
+	tx, err := d.db.Begin()
 	if err != nil {
 		return err
 	}
 
-	result, err := row.Exec(userId)
+	defer tx.Rollback()
+
+	dependents := []string{
+		"DELETE FROM triggered_alerts WHERE alert_id IN (SELECT id FROM price_alerts WHERE user_id = ?)",
+		"DELETE FROM price_alerts WHERE user_id = ?",
+		"DELETE FROM inbox_messages WHERE user_id = ?",
+		"DELETE FROM notification_queue WHERE user_id = ?",
+	}
+
+	for _, query := range dependents {
+		if _, err := tx.Exec(query, userId); err != nil {
+			return err
+		}
+	}
+
+	if err := revokeSubjectRefreshTokens(tx, userId, auth.RoleUser); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM users WHERE id = ?", userId)
 	if err != nil {
 		return err
 	}
@@ -144,28 +258,76 @@ func (d *DB) DeleteUser(userId int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("no users found with id %d", userId)
+		return NotFound("user", userId)
 	}
 
-	return nil
+	return tx.Commit()
+}
+
+// LoginUser returns the user matching the credentials. The bool is false when
+// the email is unknown or the password does not match, and both take as long
+// as a bcrypt check. Rows still holding a legacy plaintext password are
+// rehashed on a successful login.
+func (d *DB) LoginUser(request user.User) (user.User, bool, error) {
+	var stored string
+
+	singleUser := user.User{}
+	err := d.db.QueryRow("SELECT id, email, password, created_at FROM users WHERE email = ? COLLATE NOCASE", request.Email).
+		Scan(&singleUser.Id, &singleUser.Email, &stored, &singleUser.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			user.CheckUnknownAccount(request.Password)
+			return user.User{}, false, nil
+		}
+		return user.User{}, false, err
+	}
+
+	if !user.CheckPassword(stored, request.Password) {
+		return user.User{}, false, nil
+	}
+
+	if err := d.rehashLegacyPassword("users", singleUser.Id, stored, request.Password); err != nil {
+		return user.User{}, false, err
+	}
+
+	return singleUser, true, nil
 }
 
-func (d *DB) LoginUser(user user.User) (bool, error) {
-	var userExists bool
+// rehashLegacyPassword replaces a plaintext password that was just verified
+// with its hash. Already hashed passwords are left untouched.
+func (d *DB) rehashLegacyPassword(table string, id int, stored string, password string) error {
+	if user.IsHashed(stored) {
+		return nil
+	}
 
-	query := "SELECT EXISTS(SELECT 1 FROM users WHERE email=? AND password=?)"
-	err := d.db.QueryRow(query, user.Email, user.Password).Scan(&userExists)
+	hash, err := user.HashPassword(password)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		// bcrypt cannot hash it, so the legacy password stays as it is
+		return nil
+	}
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	return userExists, nil
+	_, err = d.db.Exec("UPDATE "+table+" SET password = ? WHERE id = ?", hash, id)
+	return err
+}
+
+// HashPassword is user.HashPassword with a password that is too long for
+// bcrypt reported as a validation error of the password field.
+func HashPassword(password string) (string, error) {
+	hash, err := user.HashPassword(password)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", passwordTooLong()
+	}
+
+	return hash, err
 }
 
 // ADMIN methods
 
 func (d *DB) GetAdmins() ([]user.Admin, error) {
-	rows, err := d.db.Query("SELECT * FROM admins")
+	rows, err := d.db.Query("SELECT id, email FROM admins")
 
 	if err != nil {
 		return nil, err
@@ -177,7 +339,7 @@ func (d *DB) GetAdmins() ([]user.Admin, error) {
 
 	for rows.Next() {
 		singleAdmin := user.Admin{}
-		err = rows.Scan(&singleAdmin.Id, &singleAdmin.Email, &singleAdmin.Password)
+		err = rows.Scan(&singleAdmin.Id, &singleAdmin.Email)
 
 		if err != nil {
 			return nil, err
@@ -196,14 +358,14 @@ func (d *DB) GetAdmins() ([]user.Admin, error) {
 }
 
 func (d *DB) GetAdminById(adminId int) (user.Admin, error) {
-	row := d.db.QueryRow("SELECT * FROM admins WHERE id = ?", adminId)
+	row := d.db.QueryRow("SELECT id, email FROM admins WHERE id = ?", adminId)
 
 	singleAdmin := user.Admin{}
-	err := row.Scan(&singleAdmin.Id, &singleAdmin.Email, &singleAdmin.Password)
+	err := row.Scan(&singleAdmin.Id, &singleAdmin.Email)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return user.Admin{}, fmt.Errorf("no rows found with id %d", adminId)
+			return user.Admin{}, NotFound("admin", adminId)
 		}
 		return user.Admin{}, err
 	}
@@ -212,14 +374,22 @@ func (d *DB) GetAdminById(adminId int) (user.Admin, error) {
 }
 
 func (d *DB) AddAdmin(admin user.Admin) error {
+	hash, err := HashPassword(admin.Password)
+	if err != nil {
+		return err
+	}
+
 	row, err := d.db.Prepare("INSERT INTO admins (email, password) VALUES (?, ?)")
 
 	if err != nil {
 		return err
 	}
 
-	_, err = row.Exec(admin.Email, admin.Password)
+	_, err = row.Exec(admin.Email, hash)
 	if err != nil {
+		if uniqueViolation(err) {
+			return EmailTaken(admin.Email)
+		}
 		return err
 	}
 
@@ -236,8 +406,17 @@ func (d *DB) UpdateAdmin(adminId int, request user.Admin) error {
 	}
 
 	if request.Password != "" {
+		hash, err := HashPassword(request.Password)
+		if err != nil {
+			return err
+		}
+
 		query += "password = ?, "
-		args = append(args, request.Password)
+		args = append(args, hash)
+	}
+
+	if len(args) == 0 {
+		return Invalid("no fields to update for admin with id %d", adminId)
 	}
 
 	query = strings.TrimRight(query, ", ")
@@ -250,23 +429,40 @@ func (d *DB) UpdateAdmin(adminId int, request user.Admin) error {
 		return err
 	}
 
-	_, err = row.Exec(args...)
+	result, err := row.Exec(args...)
+	if err != nil {
+		if uniqueViolation(err) {
+			return EmailTaken(request.Email)
+		}
+		return err
+	}
 
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 
+	if rowsAffected == 0 {
+		return NotFound("admin", adminId)
+	}
+
 	return nil
 }
 
+// DeleteAdmin removes the admin and revokes their refresh tokens.
 func (d *DB) DeleteAdmin(adminId int) error {
-	row, err := d.db.Prepare("DELETE FROM admins WHERE id = ?")
-
+	tx, err := d.db.Begin()
 	if err != nil {
 		return err
 	}
 
-	result, err := row.Exec(adminId)
+	defer tx.Rollback()
+
+	if err := revokeSubjectRefreshTokens(tx, adminId, auth.RoleAdmin); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM admins WHERE id = ?", adminId)
 	if err != nil {
 		return err
 	}
@@ -277,19 +473,76 @@ func (d *DB) DeleteAdmin(adminId int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("no admins found with id %d", adminId)
+		return NotFound("admin", adminId)
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-// ADMIN methods
+// LoginAdmin returns the admin matching the credentials. The bool is false
+// when the email is unknown or the password does not match, and both take as
+// long as a bcrypt check.
+func (d *DB) LoginAdmin(request user.Admin) (user.Admin, bool, error) {
+	var stored string
 
-func (d *DB) GetSneakers() ([]sneaker.Sneaker, error) {
-	rows, err := d.db.Query("SELECT * FROM sneakers")
+	admin := user.Admin{}
+	err := d.db.QueryRow("SELECT id, email, password FROM admins WHERE email = ? COLLATE NOCASE", request.Email).Scan(&admin.Id, &admin.Email, &stored)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			user.CheckUnknownAccount(request.Password)
+			return user.Admin{}, false, nil
+		}
+		return user.Admin{}, false, err
+	}
+
+	if !user.CheckPassword(stored, request.Password) {
+		return user.Admin{}, false, nil
+	}
+
+	if err := d.rehashLegacyPassword("admins", admin.Id, stored, request.Password); err != nil {
+		return user.Admin{}, false, err
+	}
+
+	return admin, true, nil
+}
+
+// SNEAKER methods
+
+var sneakerSortColumns = map[string]string{
+	"id":    "s.id",
+	"name":  "s.name",
+	"model": "s.model",
+	"brand": "s.brand",
+}
+
+// sneakerFilters applies the brand, model and name filters shared by the
+// sneaker lists. Brand and model match exactly, name matches a fragment, all
+// case-insensitively.
+func sneakerFilters(opts ListOptions) *listQuery {
+	q := &listQuery{}
+
+	if brand := opts.Filters["brand"]; brand != "" {
+		q.filter("s.brand = ? COLLATE NOCASE", brand)
+	}
+
+	if model := opts.Filters["model"]; model != "" {
+		q.filter("s.model = ? COLLATE NOCASE", model)
+	}
+
+	if name := opts.Filters["name"]; name != "" {
+		q.filter("s.name LIKE ?", "%"+name+"%")
+	}
+
+	return q
+}
+
+// GetSneakers returns a page of sneakers together with the total number of
+// sneakers matching the filters.
+func (d *DB) GetSneakers(opts ListOptions) ([]sneaker.Sneaker, int, error) {
+	rows, total, err := d.list("s.id, s.name, s.model, s.brand, s.imageUrl", "sneakers s", sneakerFilters(opts), opts, sneakerSortColumns, "s.id")
 
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -301,7 +554,7 @@ func (d *DB) GetSneakers() ([]sneaker.Sneaker, error) {
 		err = rows.Scan(&singleSneaker.Id, &singleSneaker.Name, &singleSneaker.Model, &singleSneaker.Brand, &singleSneaker.ImageUrl)
 
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		sneakers = append(sneakers, singleSneaker)
@@ -310,17 +563,153 @@ func (d *DB) GetSneakers() ([]sneaker.Sneaker, error) {
 	err = rows.Err()
 
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return sneakers, nil
+	return sneakers, total, nil
 }
 
-func (d *DB) GetSneakersInfo() ([]sneaker.SneakerInformation, error) {
-	rows, err := d.db.Query("SELECT s.*, si.* FROM sneakers s JOIN sneakers_information si ON s.id = si.sneakerId")
+func (d *DB) GetSneakerById(sneakerId int) (sneaker.Sneaker, error) {
+	row := d.db.QueryRow("SELECT * FROM sneakers WHERE id = ?", sneakerId)
+
+	singleSneaker := sneaker.Sneaker{}
+	err := row.Scan(&singleSneaker.Id, &singleSneaker.Name, &singleSneaker.Model, &singleSneaker.Brand, &singleSneaker.ImageUrl)
 
 	if err != nil {
-		return nil, err
+		if err == sql.ErrNoRows {
+			return sneaker.Sneaker{}, NotFound("sneaker", sneakerId)
+		}
+		return sneaker.Sneaker{}, err
+	}
+
+	return singleSneaker, nil
+}
+
+func (d *DB) AddSneaker(sneaker sneaker.Sneaker) (int, error) {
+	row, err := d.db.Prepare("INSERT INTO sneakers (name, model, brand, imageUrl) VALUES (?, ?, ?, ?)")
+
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := row.Exec(sneaker.Name, sneaker.Model, sneaker.Brand, sneaker.ImageUrl)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (d *DB) UpdateSneaker(sneakerId int, request sneaker.Sneaker) error {
+	query := "UPDATE sneakers SET "
+	var args []interface{}
+
+	if request.Name != "" {
+		query += "name = ?, "
+		args = append(args, request.Name)
+	}
+
+	if request.Model != "" {
+		query += "model = ?, "
+		args = append(args, request.Model)
+	}
+
+	if request.Brand != "" {
+		query += "brand = ?, "
+		args = append(args, request.Brand)
+	}
+
+	if request.ImageUrl != "" {
+		query += "imageUrl = ?, "
+		args = append(args, request.ImageUrl)
+	}
+
+	if len(args) == 0 {
+		return Invalid("no fields to update for sneaker with id %d", sneakerId)
+	}
+
+	query = strings.TrimRight(query, ", ")
+	query += " WHERE id = ?"
+	args = append(args, sneakerId)
+
+	row, err := d.db.Prepare(query)
+
+	if err != nil {
+		return err
+	}
+
+	result, err := row.Exec(args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return NotFound("sneaker", sneakerId)
+	}
+
+	return nil
+}
+
+// DeleteSneaker removes the sneaker together with its information, provider
+// availability, scrapper, price history and alert rows in a single transaction.
+func (d *DB) DeleteSneaker(sneakerId int) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	dependents := []string{
+		"DELETE FROM sneakers_information WHERE sneakerId = ?",
+		"DELETE FROM provider_information WHERE product_id = ?",
+		"DELETE FROM provider_size_information WHERE product_id = ?",
+		"DELETE FROM availability_scrappers WHERE product_id = ?",
+		"DELETE FROM price_history WHERE product_id = ?",
+		"DELETE FROM triggered_alerts WHERE alert_id IN (SELECT id FROM price_alerts WHERE sneaker_id = ?)",
+		"DELETE FROM price_alerts WHERE sneaker_id = ?",
+	}
+
+	for _, query := range dependents {
+		if _, err := tx.Exec(query, sneakerId); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec("DELETE FROM sneakers WHERE id = ?", sneakerId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return NotFound("sneaker", sneakerId)
+	}
+
+	return tx.Commit()
+}
+
+// GetSneakersInfo returns a page of sneakers with their information together
+// with the total number of such sneakers matching the filters.
+func (d *DB) GetSneakersInfo(opts ListOptions) ([]sneaker.SneakerInformation, int, error) {
+	rows, total, err := d.list("s.*, si.*", "sneakers s JOIN sneakers_information si ON s.id = si.sneakerId", sneakerFilters(opts), opts, sneakerSortColumns, "s.id")
+
+	if err != nil {
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -334,7 +723,7 @@ func (d *DB) GetSneakersInfo() ([]sneaker.SneakerInformation, error) {
 			&singleSneaker.SneakerInformation.MainImageUrl, &singleSneaker.SneakerInformation.AdditionalInfo)
 
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		sneakers = append(sneakers, singleSneaker)
@@ -343,17 +732,19 @@ func (d *DB) GetSneakersInfo() ([]sneaker.SneakerInformation, error) {
 	err = rows.Err()
 
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return sneakers, nil
+	return sneakers, total, nil
 }
 
+// GetSneakerInfo returns the sneaker with its information. A sneaker that
+// has no sneakers_information row yet comes back with empty information.
 func (d *DB) GetSneakerInfo(sneakerId int) (*sneaker.SneakerInformation, error) {
 	query := `
-        SELECT s.*, si.*
+        SELECT s.*, s.id, COALESCE(si.mainInfo, ''), COALESCE(si.mainImageUrl, ''), COALESCE(si.additionalInfo, '')
         FROM sneakers s
-        JOIN sneakers_information si ON s.id = si.sneakerId
+        LEFT JOIN sneakers_information si ON s.id = si.sneakerId
         WHERE s.id = ?;
     `
 	row := d.db.QueryRow(query, sneakerId)
@@ -362,13 +753,19 @@ func (d *DB) GetSneakerInfo(sneakerId int) (*sneaker.SneakerInformation, error)
 	err := row.Scan(&sneaker.Id, &sneaker.Name, &sneaker.Model, &sneaker.Brand, &sneaker.ImageUrl, &sneaker.SneakerInformation.SneakerId,
 		&sneaker.SneakerInformation.MainInfo, &sneaker.SneakerInformation.MainImageUrl, &sneaker.SneakerInformation.AdditionalInfo)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, NotFound("sneaker", sneakerId)
+		}
 		return nil, err
 	}
 
 	return sneaker, nil
 }
 
-func (d *DB) GetSneakersAvailability() ([]sneaker.SneakerAvailability, error) {
+// GetSneakersAvailability returns every sneaker with its provider
+// availability and the sizes each provider lists. A non-zero sizeUS keeps
+// only the providers that have that size in stock.
+func (d *DB) GetSneakersAvailability(sizeUS float64) ([]sneaker.SneakerAvailability, error) {
 	rows, err := d.db.Query("SELECT s.*, pi.* FROM sneakers s JOIN provider_information pi ON s.id = pi.product_id")
 
 	if err != nil {
@@ -377,6 +774,11 @@ func (d *DB) GetSneakersAvailability() ([]sneaker.SneakerAvailability, error) {
 
 	defer rows.Close()
 
+	sizes, err := d.getProviderSizes()
+	if err != nil {
+		return nil, err
+	}
+
 	sneakersMap := make(map[int]sneaker.SneakerAvailability)
 
 	for rows.Next() {
@@ -390,6 +792,18 @@ func (d *DB) GetSneakersAvailability() ([]sneaker.SneakerAvailability, error) {
 			return nil, err
 		}
 
+		availability.Sizes = sizes[[2]int{availability.ProductId, availability.ProviderId}]
+
+		if sizeUS != 0 {
+			size, ok := findSize(availability.Sizes, sizeUS)
+			if !ok || !size.Available {
+				continue
+			}
+			availability.Price = size.Price
+			availability.Available = true
+			availability.Sizes = []sneaker.SizeAvailability{size}
+		}
+
 		// Check if we've already added the sneaker to the map
 		if existingSneaker, ok := sneakersMap[singleSneaker.Id]; ok {
 			// Add the availability to the existing sneaker's array
@@ -402,6 +816,10 @@ func (d *DB) GetSneakersAvailability() ([]sneaker.SneakerAvailability, error) {
 		}
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
 	// Convert the map to an array
 	sneakers := make([]sneaker.SneakerAvailability, 0, len(sneakersMap))
 	for _, value := range sneakersMap {
@@ -411,6 +829,45 @@ func (d *DB) GetSneakersAvailability() ([]sneaker.SneakerAvailability, error) {
 	return sneakers, nil
 }
 
+// getProviderSizes loads every size row keyed by product and provider id.
+func (d *DB) getProviderSizes() (map[[2]int][]sneaker.SizeAvailability, error) {
+	rows, err := d.db.Query("SELECT product_id, provider_id, size_us, price, available FROM provider_size_information ORDER BY product_id, provider_id, size_us")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	sizes := make(map[[2]int][]sneaker.SizeAvailability)
+
+	for rows.Next() {
+		var productId, providerId int
+		var us float64
+		var price float32
+		var available bool
+
+		if err := rows.Scan(&productId, &providerId, &us, &price, &available); err != nil {
+			return nil, err
+		}
+
+		key := [2]int{productId, providerId}
+		sizes[key] = append(sizes[key], sneaker.NewSizeAvailability(us, price, available))
+	}
+
+	return sizes, rows.Err()
+}
+
+func findSize(sizes []sneaker.SizeAvailability, us float64) (sneaker.SizeAvailability, bool) {
+	for _, size := range sizes {
+		if size.US == us {
+			return size, true
+		}
+	}
+
+	return sneaker.SizeAvailability{}, false
+}
+
 func (d *DB) GetSneakerScrapper(sneakerId int) ([]sneaker.AvailabilityScrappers, error) {
 	query := `
         SELECT s.*, avs.*
@@ -459,10 +916,132 @@ func (d *DB) GetSneakerScrapper(sneakerId int) ([]sneaker.AvailabilityScrappers,
 	return sneakers, nil
 }
 
-// PROVIDER methods
+func (d *DB) GetScrappers() ([]sneaker.Scrapper, error) {
+	rows, err := d.db.Query("SELECT id, product_id, provider_id, search_for FROM availability_scrappers")
 
-func (d *DB) GetProviders() ([]provider.ProviderInformation, error) {
-	rows, err := d.db.Query("SELECT * FROM product_providers")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	scrappers := make([]sneaker.Scrapper, 0)
+
+	for rows.Next() {
+		scrapper := sneaker.Scrapper{}
+		err = rows.Scan(&scrapper.Id, &scrapper.ProductId, &scrapper.ProviderId, &scrapper.SearchFor)
+
+		if err != nil {
+			return nil, err
+		}
+
+		scrappers = append(scrappers, scrapper)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scrappers, nil
+}
+
+// UpsertProviderInformation stores the current price and availability of a
+// product at a provider, updating the existing row when there is one. Every
+// change is also appended to price_history and checked against price alerts.
+func (d *DB) UpsertProviderInformation(availability sneaker.Availability) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if availability.Sizes != nil {
+		if err := replaceProviderSizes(tx, availability); err != nil {
+			return err
+		}
+	}
+
+	current := sneaker.Availability{}
+	err = tx.QueryRow("SELECT id, price, available FROM provider_information WHERE product_id = ? AND provider_id = ?",
+		availability.ProductId, availability.ProviderId).Scan(&current.Id, &current.Price, &current.Available)
+
+	var previous *sneaker.Availability
+
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = tx.Exec("INSERT INTO provider_information (product_id, provider_id, price, available) VALUES (?, ?, ?, ?)",
+			availability.ProductId, availability.ProviderId, availability.Price, availability.Available)
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case current.Price == availability.Price && current.Available == availability.Available:
+		return tx.Commit()
+	default:
+		_, err = tx.Exec("UPDATE provider_information SET price = ?, available = ? WHERE id = ?",
+			availability.Price, availability.Available, current.Id)
+		if err != nil {
+			return err
+		}
+		previous = &current
+	}
+
+	_, err = tx.Exec("INSERT INTO price_history (product_id, provider_id, price, available) VALUES (?, ?, ?, ?)",
+		availability.ProductId, availability.ProviderId, availability.Price, availability.Available)
+	if err != nil {
+		return err
+	}
+
+	if err := d.evaluatePriceAlerts(tx, previous, availability); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// replaceProviderSizes swaps the size rows of a provider for the sizes in
+// availability, so sizes a provider stopped listing disappear.
+func replaceProviderSizes(tx *sql.Tx, availability sneaker.Availability) error {
+	_, err := tx.Exec("DELETE FROM provider_size_information WHERE product_id = ? AND provider_id = ?",
+		availability.ProductId, availability.ProviderId)
+	if err != nil {
+		return err
+	}
+
+	for _, size := range availability.Sizes {
+		_, err = tx.Exec("INSERT OR REPLACE INTO provider_size_information (product_id, provider_id, size_us, price, available) VALUES (?, ?, ?, ?, ?)",
+			availability.ProductId, availability.ProviderId, size.US, size.Price, size.Available)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetPriceHistory returns the recorded price changes of a sneaker grouped by
+// provider in chronological order. Zero from or to leave that end open.
+func (d *DB) GetPriceHistory(sneakerId int, from time.Time, to time.Time) ([]sneaker.PriceHistory, error) {
+	query := "SELECT provider_id, price, available, recorded_at FROM price_history WHERE product_id = ?"
+	args := []interface{}{sneakerId}
+
+	if !from.IsZero() {
+		query += " AND recorded_at >= ?"
+		args = append(args, from.UTC().Format(sqliteTimeFormat))
+	}
+
+	if !to.IsZero() {
+		query += " AND recorded_at < ?"
+		args = append(args, to.UTC().Format(sqliteTimeFormat))
+	}
+
+	query += " ORDER BY provider_id, recorded_at, id"
+
+	rows, err := d.db.Query(query, args...)
 
 	if err != nil {
 		return nil, err
@@ -470,6 +1049,58 @@ func (d *DB) GetProviders() ([]provider.ProviderInformation, error) {
 
 	defer rows.Close()
 
+	history := make([]sneaker.PriceHistory, 0)
+
+	for rows.Next() {
+		var providerId int
+		point := sneaker.PricePoint{}
+		err = rows.Scan(&providerId, &point.Price, &point.Available, &point.RecordedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Rows are ordered by provider, so a new provider starts a new series
+		if len(history) == 0 || history[len(history)-1].ProviderId != providerId {
+			history = append(history, sneaker.PriceHistory{ProviderId: providerId})
+		}
+
+		last := &history[len(history)-1]
+		last.Points = append(last.Points, point)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// PROVIDER methods
+
+var providerSortColumns = map[string]string{
+	"id":           "id",
+	"providerName": "provider_name",
+}
+
+// GetProviders returns a page of providers together with the total number
+// of providers matching the name filter.
+func (d *DB) GetProviders(opts ListOptions) ([]provider.ProviderInformation, int, error) {
+	q := &listQuery{}
+	if name := opts.Filters["name"]; name != "" {
+		q.filter("provider_name LIKE ?", "%"+name+"%")
+	}
+
+	rows, total, err := d.list("id, provider_name", "product_providers", q, opts, providerSortColumns, "id")
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
 	providers := make([]provider.ProviderInformation, 0)
 
 	for rows.Next() {
@@ -477,7 +1108,7 @@ func (d *DB) GetProviders() ([]provider.ProviderInformation, error) {
 		err = rows.Scan(&singleProvider.Id, &singleProvider.ProviderName)
 
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		providers = append(providers, singleProvider)
@@ -486,10 +1117,10 @@ func (d *DB) GetProviders() ([]provider.ProviderInformation, error) {
 	err = rows.Err()
 
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return providers, nil
+	return providers, total, nil
 }
 
 func (d *DB) GetProviderById(providerId int) (provider.ProviderInformation, error) {
@@ -500,10 +1131,118 @@ func (d *DB) GetProviderById(providerId int) (provider.ProviderInformation, erro
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return provider.ProviderInformation{}, fmt.Errorf("no rows found with id %d", providerId)
+			return provider.ProviderInformation{}, NotFound("provider", providerId)
 		}
 		return provider.ProviderInformation{}, err
 	}
 
 	return singleProvider, nil
 }
+
+func (d *DB) AddProvider(provider provider.ProviderInformation) (int, error) {
+	row, err := d.db.Prepare("INSERT INTO product_providers (provider_name) VALUES (?)")
+
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := row.Exec(provider.ProviderName)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (d *DB) UpdateProvider(providerId int, request provider.ProviderInformation) error {
+	if request.ProviderName == "" {
+		return Invalid("no fields to update for provider with id %d", providerId)
+	}
+
+	row, err := d.db.Prepare("UPDATE product_providers SET provider_name = ? WHERE id = ?")
+
+	if err != nil {
+		return err
+	}
+
+	result, err := row.Exec(request.ProviderName, providerId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return NotFound("provider", providerId)
+	}
+
+	return nil
+}
+
+// DeleteProvider removes a provider. Unless cascade is set, the provider is
+// kept and ErrProviderInUse returned while availability, scrapper, price
+// history or alert rows still reference it.
+func (d *DB) DeleteProvider(providerId int, cascade bool) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	// ?1 is the provider id. Triggers go before the alerts they belong to.
+	dependents := []struct {
+		table string
+		where string
+	}{
+		{"provider_information", "provider_id = ?1"},
+		{"provider_size_information", "provider_id = ?1"},
+		{"availability_scrappers", "provider_id = ?1"},
+		{"price_history", "provider_id = ?1"},
+		{"triggered_alerts", "provider_id = ?1 OR alert_id IN (SELECT id FROM price_alerts WHERE provider_id = ?1)"},
+		{"price_alerts", "provider_id = ?1"},
+	}
+
+	for _, dependent := range dependents {
+		if cascade {
+			if _, err := tx.Exec("DELETE FROM "+dependent.table+" WHERE "+dependent.where, providerId); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var inUse bool
+		err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM "+dependent.table+" WHERE "+dependent.where+")", providerId).Scan(&inUse)
+		if err != nil {
+			return err
+		}
+
+		if inUse {
+			return ErrProviderInUse
+		}
+	}
+
+	result, err := tx.Exec("DELETE FROM product_providers WHERE id = ?", providerId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return NotFound("provider", providerId)
+	}
+
+	return tx.Commit()
+}
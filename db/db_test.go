@@ -0,0 +1,97 @@
+package db
+
+import (
+	"errors"
+	"github.com/Gretamass/kys-backend/provider"
+	"github.com/Gretamass/kys-backend/sneaker"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// openTestDB connects to a migrated database in a temporary file.
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	d, err := ConnectDatabase(filepath.Join(t.TempDir(), "kys.db"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+
+	return d
+}
+
+func TestConcurrentUpsertProviderInformation(t *testing.T) {
+	d := openTestDB(t)
+
+	sneakerId, err := d.AddSneaker(sneaker.Sneaker{Name: "Dunk Low", Model: "Panda", Brand: "Nike"})
+	if err != nil {
+		t.Fatalf("add sneaker: %v", err)
+	}
+
+	var providerIds []int
+	for _, name := range []string{"Shop", "Store"} {
+		id, err := d.AddProvider(provider.ProviderInformation{ProviderName: name})
+		if err != nil {
+			t.Fatalf("add provider: %v", err)
+		}
+		providerIds = append(providerIds, id)
+	}
+
+	const upserts = 80
+
+	var wg sync.WaitGroup
+	errs := make(chan error, upserts)
+
+	for i := 0; i < upserts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			errs <- d.UpsertProviderInformation(sneaker.Availability{
+				ProductId:  sneakerId,
+				ProviderId: providerIds[i%len(providerIds)],
+				Price:      float32(100 + i),
+				Available:  i%3 != 0,
+				Sizes:      []sneaker.SizeAvailability{sneaker.NewSizeAvailability(9, float32(100+i), true)},
+			})
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("upsert: %v", err)
+		}
+	}
+
+	history, err := d.GetPriceHistory(sneakerId, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("price history: %v", err)
+	}
+
+	points := 0
+	for _, series := range history {
+		points += len(series.Points)
+	}
+
+	// every upsert changed the price, so each one is recorded
+	if points != upserts {
+		t.Errorf("price history has %d points, want %d", points, upserts)
+	}
+}
+
+func TestHashPasswordRejectsLongPasswords(t *testing.T) {
+	// 41 characters but 81 bytes
+	_, err := HashPassword(strings.Repeat("é", 40) + "1")
+
+	var dbErr *Error
+	if !errors.Is(err, ErrValidation) || !errors.As(err, &dbErr) || dbErr.Field != "password" {
+		t.Fatalf("err = %v, want a validation error of the password field", err)
+	}
+}
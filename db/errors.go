@@ -0,0 +1,71 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Gretamass/kys-backend/user"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Error kinds returned by the store methods. Check them with errors.Is.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+)
+
+// Error is an error of one of the kinds above with a message that is safe
+// to show to API clients. The constructors below are shared with other store
+// implementations so they report the same errors.
+type Error struct {
+	Kind    error
+	Message string
+	// Field is the JSON name of the field that caused the error, if any.
+	Field string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// NotFound reports that no resource of the given kind has id.
+func NotFound(resource string, id int) error {
+	return &Error{Kind: ErrNotFound, Message: fmt.Sprintf("%s with id %d not found", resource, id)}
+}
+
+// Conflict reports a request that clashes with the stored data.
+func Conflict(format string, args ...interface{}) error {
+	return &Error{Kind: ErrConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// uniqueViolation reports whether err is a failed UNIQUE constraint.
+func uniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// EmailTaken is the conflict returned when an email is already in use.
+func EmailTaken(email string) error {
+	return &Error{Kind: ErrConflict, Message: fmt.Sprintf("email %s is already in use", email), Field: "email"}
+}
+
+// UnknownReference is the validation error for a field that refers to a
+// resource that does not exist.
+func UnknownReference(field string, resource string, id int) error {
+	return &Error{Kind: ErrValidation, Message: fmt.Sprintf("%s with id %d does not exist", resource, id), Field: field}
+}
+
+// passwordTooLong is the validation error for a password bcrypt cannot hash.
+func passwordTooLong() error {
+	return &Error{Kind: ErrValidation, Message: fmt.Sprintf("password must be at most %d bytes", user.MaxPasswordBytes), Field: "password"}
+}
+
+// Invalid reports a request that cannot be applied as it is.
+func Invalid(format string, args ...interface{}) error {
+	return &Error{Kind: ErrValidation, Message: fmt.Sprintf(format, args...)}
+}
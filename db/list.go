@@ -0,0 +1,95 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSort is returned when a list is sorted by a field that is not
+// whitelisted for it.
+var ErrInvalidSort = errors.New("invalid sort field")
+
+// ListOptions controls paging, sorting and filtering of list queries.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// Sort is the JSON field name to order by; the default is id.
+	Sort string
+	Desc bool
+	// Filters holds the filters supported by the list method, for example
+	// brand, model and name on sneakers.
+	Filters map[string]string
+}
+
+// listQuery assembles the WHERE, ORDER BY and LIMIT parts of a list query.
+type listQuery struct {
+	where []string
+	args  []interface{}
+}
+
+func (q *listQuery) filter(condition string, arg interface{}) {
+	q.where = append(q.where, condition)
+	q.args = append(q.args, arg)
+}
+
+func (q *listQuery) whereClause() string {
+	if len(q.where) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(q.where, " AND ")
+}
+
+// orderClause maps opts.Sort through the whitelist of sortable columns.
+func (q *listQuery) orderClause(opts ListOptions, columns map[string]string, idColumn string) (string, error) {
+	column := idColumn
+	if opts.Sort != "" {
+		var ok bool
+		if column, ok = columns[opts.Sort]; !ok {
+			return "", fmt.Errorf("%w: %s", ErrInvalidSort, opts.Sort)
+		}
+	}
+
+	direction := "ASC"
+	if opts.Desc {
+		direction = "DESC"
+	}
+
+	// id breaks ties so pages do not overlap
+	return fmt.Sprintf(" ORDER BY %s %s, %s %s", column, direction, idColumn, direction), nil
+}
+
+func (q *listQuery) limitClause(opts ListOptions) (string, []interface{}) {
+	if opts.Limit <= 0 {
+		return "", nil
+	}
+
+	return " LIMIT ? OFFSET ?", []interface{}{opts.Limit, opts.Offset}
+}
+
+// list counts the rows of from matching q and returns the requested page of
+// them selected with columnList.
+func (d *DB) list(columnList string, from string, q *listQuery, opts ListOptions, sortColumns map[string]string, idColumn string) (*sql.Rows, int, error) {
+	order, err := q.orderClause(opts, sortColumns, idColumn)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = d.db.QueryRow("SELECT COUNT(*) FROM "+from+q.whereClause(), q.args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	limit, limitArgs := q.limitClause(opts)
+	args := append(append([]interface{}{}, q.args...), limitArgs...)
+
+	rows, err := d.db.Query("SELECT "+columnList+" FROM "+from+q.whereClause()+order+limit, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return rows, total, nil
+}
@@ -0,0 +1,851 @@
+// Package memory is an in-memory implementation of the db store interfaces
+// for handler tests. It mirrors the behaviour and errors of db.DB but keeps
+// no availability, scrapper or notification data, so alerts never trigger.
+package memory
+
+import (
+	"fmt"
+	"github.com/Gretamass/kys-backend/alert"
+	"github.com/Gretamass/kys-backend/auth"
+	"github.com/Gretamass/kys-backend/db"
+	"github.com/Gretamass/kys-backend/provider"
+	"github.com/Gretamass/kys-backend/sneaker"
+	"github.com/Gretamass/kys-backend/user"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type storedUser struct {
+	user.User
+	password string
+}
+
+type storedAdmin struct {
+	user.Admin
+	password string
+}
+
+type storedRefreshToken struct {
+	auth.RefreshToken
+	used    bool
+	revoked bool
+}
+
+// Store holds users, admins, sneakers with their information, providers,
+// price alerts and refresh tokens.
+type Store struct {
+	mu sync.Mutex
+
+	users     []storedUser
+	admins    []storedAdmin
+	sneakers  []sneaker.Sneaker
+	info      map[int]sneaker.SneakerInfo
+	providers []provider.ProviderInformation
+	// providersInUse marks providers that DeleteProvider refuses to remove
+	// without cascade, standing in for availability and scrapper rows.
+	providersInUse map[int]bool
+	alerts         []alert.PriceAlert
+	refreshTokens  map[string]*storedRefreshToken
+
+	nextUserId     int
+	nextAdminId    int
+	nextSneakerId  int
+	nextProviderId int
+	nextAlertId    int
+}
+
+var (
+	_ db.UserStore     = (*Store)(nil)
+	_ db.AdminStore    = (*Store)(nil)
+	_ db.SneakerStore  = (*Store)(nil)
+	_ db.ProviderStore = (*Store)(nil)
+	_ db.AlertStore    = (*Store)(nil)
+
+	_ db.RefreshTokenStore = (*Store)(nil)
+)
+
+func New() *Store {
+	return &Store{
+		info:           make(map[int]sneaker.SneakerInfo),
+		providersInUse: make(map[int]bool),
+		refreshTokens:  make(map[string]*storedRefreshToken),
+		nextUserId:     1,
+		nextAdminId:    1,
+		nextSneakerId:  1,
+		nextProviderId: 1,
+		nextAlertId:    1,
+	}
+}
+
+// page filters, sorts and slices items the way db.DB list queries do.
+// columns maps the sortable fields to a comparison of two items.
+func page[T any](items []T, keep func(T) bool, opts db.ListOptions, columns map[string]func(a, b T) int, id func(T) int) ([]T, int, error) {
+	compare := func(a, b T) int { return 0 }
+	if opts.Sort != "" {
+		var ok bool
+		if compare, ok = columns[opts.Sort]; !ok {
+			return nil, 0, fmt.Errorf("%w: %s", db.ErrInvalidSort, opts.Sort)
+		}
+	}
+
+	matched := make([]T, 0)
+	for _, item := range items {
+		if keep(item) {
+			matched = append(matched, item)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		order := compare(matched[i], matched[j])
+		if order == 0 {
+			order = id(matched[i]) - id(matched[j])
+		}
+		if opts.Desc {
+			return order > 0
+		}
+		return order < 0
+	})
+
+	total := len(matched)
+
+	if opts.Limit > 0 {
+		start := opts.Offset
+		if start > total {
+			start = total
+		}
+		end := start + opts.Limit
+		if end > total {
+			end = total
+		}
+		matched = matched[start:end]
+	}
+
+	return matched, total, nil
+}
+
+// contains matches like SQLite's LIKE '%fragment%', ignoring ASCII case.
+func contains(value string, fragment string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(fragment))
+}
+
+func compareInts(a, b int) int {
+	return a - b
+}
+
+// USER methods
+
+func (s *Store) GetUsers(opts db.ListOptions) ([]user.User, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email := opts.Filters["email"]
+
+	stored, total, err := page(s.users, func(u storedUser) bool {
+		return email == "" || contains(u.Email, email)
+	}, opts, map[string]func(a, b storedUser) int{
+		"id":        func(a, b storedUser) int { return compareInts(a.Id, b.Id) },
+		"email":     func(a, b storedUser) int { return strings.Compare(a.Email, b.Email) },
+		"createdAt": func(a, b storedUser) int { return strings.Compare(a.CreatedAt, b.CreatedAt) },
+	}, func(u storedUser) int { return u.Id })
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users := make([]user.User, 0, len(stored))
+	for _, u := range stored {
+		users = append(users, u.User)
+	}
+
+	return users, total, nil
+}
+
+func (s *Store) GetUserById(userId int) (user.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Id == userId {
+			return u.User, nil
+		}
+	}
+
+	return user.User{}, db.NotFound("user", userId)
+}
+
+func (s *Store) AddUser(newUser user.User) error {
+	hash, err := db.HashPassword(newUser.Password)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, newUser.Email) {
+			return db.EmailTaken(newUser.Email)
+		}
+	}
+
+	newUser.Id = s.nextUserId
+	newUser.Password = ""
+	newUser.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	s.nextUserId++
+
+	s.users = append(s.users, storedUser{User: newUser, password: hash})
+
+	return nil
+}
+
+func (s *Store) UpdateUser(userId int, request user.User) error {
+	if request.Email == "" && request.Password == "" {
+		return db.Invalid("no fields to update for user with id %d", userId)
+	}
+
+	var hash string
+	if request.Password != "" {
+		var err error
+		if hash, err = db.HashPassword(request.Password); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, other := range s.users {
+		if other.Id != userId && request.Email != "" && strings.EqualFold(other.Email, request.Email) {
+			return db.EmailTaken(request.Email)
+		}
+	}
+
+	for i := range s.users {
+		if s.users[i].Id != userId {
+			continue
+		}
+		if request.Email != "" {
+			s.users[i].Email = request.Email
+		}
+		if hash != "" {
+			s.users[i].password = hash
+		}
+		return nil
+	}
+
+	return db.NotFound("user", userId)
+}
+
+func (s *Store) DeleteUser(userId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, u := range s.users {
+		if u.Id == userId {
+			s.users = append(s.users[:i], s.users[i+1:]...)
+			s.deleteAlerts(func(a alert.PriceAlert) bool { return a.UserId == userId })
+			s.revokeSubject(userId, auth.RoleUser)
+			return nil
+		}
+	}
+
+	return db.NotFound("user", userId)
+}
+
+func (s *Store) LoginUser(request user.User) (user.User, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, request.Email) {
+			if !user.CheckPassword(u.password, request.Password) {
+				return user.User{}, false, nil
+			}
+			return u.User, true, nil
+		}
+	}
+
+	user.CheckUnknownAccount(request.Password)
+	return user.User{}, false, nil
+}
+
+// ADMIN methods
+
+func (s *Store) GetAdmins() ([]user.Admin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admins := make([]user.Admin, 0, len(s.admins))
+	for _, a := range s.admins {
+		admins = append(admins, a.Admin)
+	}
+
+	return admins, nil
+}
+
+func (s *Store) GetAdminById(adminId int) (user.Admin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range s.admins {
+		if a.Id == adminId {
+			return a.Admin, nil
+		}
+	}
+
+	return user.Admin{}, db.NotFound("admin", adminId)
+}
+
+func (s *Store) AddAdmin(admin user.Admin) error {
+	hash, err := db.HashPassword(admin.Password)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range s.admins {
+		if strings.EqualFold(a.Email, admin.Email) {
+			return db.EmailTaken(admin.Email)
+		}
+	}
+
+	admin.Id = s.nextAdminId
+	admin.Password = ""
+	s.nextAdminId++
+
+	s.admins = append(s.admins, storedAdmin{Admin: admin, password: hash})
+
+	return nil
+}
+
+func (s *Store) UpdateAdmin(adminId int, request user.Admin) error {
+	if request.Email == "" && request.Password == "" {
+		return db.Invalid("no fields to update for admin with id %d", adminId)
+	}
+
+	var hash string
+	if request.Password != "" {
+		var err error
+		if hash, err = db.HashPassword(request.Password); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, other := range s.admins {
+		if other.Id != adminId && request.Email != "" && strings.EqualFold(other.Email, request.Email) {
+			return db.EmailTaken(request.Email)
+		}
+	}
+
+	for i := range s.admins {
+		if s.admins[i].Id != adminId {
+			continue
+		}
+		if request.Email != "" {
+			s.admins[i].Email = request.Email
+		}
+		if hash != "" {
+			s.admins[i].password = hash
+		}
+		return nil
+	}
+
+	return db.NotFound("admin", adminId)
+}
+
+func (s *Store) DeleteAdmin(adminId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, a := range s.admins {
+		if a.Id == adminId {
+			s.admins = append(s.admins[:i], s.admins[i+1:]...)
+			s.revokeSubject(adminId, auth.RoleAdmin)
+			return nil
+		}
+	}
+
+	return db.NotFound("admin", adminId)
+}
+
+func (s *Store) LoginAdmin(request user.Admin) (user.Admin, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range s.admins {
+		if strings.EqualFold(a.Email, request.Email) {
+			if !user.CheckPassword(a.password, request.Password) {
+				return user.Admin{}, false, nil
+			}
+			return a.Admin, true, nil
+		}
+	}
+
+	user.CheckUnknownAccount(request.Password)
+	return user.Admin{}, false, nil
+}
+
+// SNEAKER methods
+
+var sneakerColumns = map[string]func(a, b sneaker.Sneaker) int{
+	"id":    func(a, b sneaker.Sneaker) int { return compareInts(a.Id, b.Id) },
+	"name":  func(a, b sneaker.Sneaker) int { return strings.Compare(a.Name, b.Name) },
+	"model": func(a, b sneaker.Sneaker) int { return strings.Compare(a.Model, b.Model) },
+	"brand": func(a, b sneaker.Sneaker) int { return strings.Compare(a.Brand, b.Brand) },
+}
+
+// sneakerFilter mirrors the brand, model and name filters of db.DB.
+func sneakerFilter(opts db.ListOptions) func(sneaker.Sneaker) bool {
+	brand, model, name := opts.Filters["brand"], opts.Filters["model"], opts.Filters["name"]
+
+	return func(item sneaker.Sneaker) bool {
+		return (brand == "" || strings.EqualFold(item.Brand, brand)) &&
+			(model == "" || strings.EqualFold(item.Model, model)) &&
+			(name == "" || contains(item.Name, name))
+	}
+}
+
+func (s *Store) GetSneakers(opts db.ListOptions) ([]sneaker.Sneaker, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return page(s.sneakers, sneakerFilter(opts), opts, sneakerColumns, func(item sneaker.Sneaker) int { return item.Id })
+}
+
+func (s *Store) AddSneaker(newSneaker sneaker.Sneaker) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	newSneaker.Id = s.nextSneakerId
+	s.nextSneakerId++
+
+	s.sneakers = append(s.sneakers, newSneaker)
+
+	return newSneaker.Id, nil
+}
+
+// SetSneakerInfo stores the information of a sneaker, which db.DB only
+// reads from the sneakers_information table.
+func (s *Store) SetSneakerInfo(info sneaker.SneakerInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.info[info.SneakerId] = info
+}
+
+func (s *Store) UpdateSneaker(sneakerId int, request sneaker.Sneaker) error {
+	if request.Name == "" && request.Model == "" && request.Brand == "" && request.ImageUrl == "" {
+		return db.Invalid("no fields to update for sneaker with id %d", sneakerId)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.sneakers {
+		if s.sneakers[i].Id != sneakerId {
+			continue
+		}
+		if request.Name != "" {
+			s.sneakers[i].Name = request.Name
+		}
+		if request.Model != "" {
+			s.sneakers[i].Model = request.Model
+		}
+		if request.Brand != "" {
+			s.sneakers[i].Brand = request.Brand
+		}
+		if request.ImageUrl != "" {
+			s.sneakers[i].ImageUrl = request.ImageUrl
+		}
+		return nil
+	}
+
+	return db.NotFound("sneaker", sneakerId)
+}
+
+func (s *Store) DeleteSneaker(sneakerId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, item := range s.sneakers {
+		if item.Id == sneakerId {
+			s.sneakers = append(s.sneakers[:i], s.sneakers[i+1:]...)
+			delete(s.info, sneakerId)
+			s.deleteAlerts(func(a alert.PriceAlert) bool { return a.SneakerId == sneakerId })
+			return nil
+		}
+	}
+
+	return db.NotFound("sneaker", sneakerId)
+}
+
+func (s *Store) GetSneakersInfo(opts db.ListOptions) ([]sneaker.SneakerInformation, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filter := sneakerFilter(opts)
+	matched, total, err := page(s.sneakers, func(item sneaker.Sneaker) bool {
+		_, ok := s.info[item.Id]
+		return ok && filter(item)
+	}, opts, sneakerColumns, func(item sneaker.Sneaker) int { return item.Id })
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sneakers := make([]sneaker.SneakerInformation, 0, len(matched))
+	for _, item := range matched {
+		sneakers = append(sneakers, s.information(item))
+	}
+
+	return sneakers, total, nil
+}
+
+func (s *Store) GetSneakerInfo(sneakerId int) (*sneaker.SneakerInformation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range s.sneakers {
+		if item.Id == sneakerId {
+			information := s.information(item)
+			information.SneakerInformation.SneakerId = item.Id
+			return &information, nil
+		}
+	}
+
+	return nil, db.NotFound("sneaker", sneakerId)
+}
+
+func (s *Store) information(item sneaker.Sneaker) sneaker.SneakerInformation {
+	return sneaker.SneakerInformation{
+		Id:                 item.Id,
+		Name:               item.Name,
+		Model:              item.Model,
+		Brand:              item.Brand,
+		ImageUrl:           item.ImageUrl,
+		SneakerInformation: s.info[item.Id],
+	}
+}
+
+// PROVIDER methods
+
+func (s *Store) GetProviders(opts db.ListOptions) ([]provider.ProviderInformation, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := opts.Filters["name"]
+
+	return page(s.providers, func(item provider.ProviderInformation) bool {
+		return name == "" || contains(item.ProviderName, name)
+	}, opts, map[string]func(a, b provider.ProviderInformation) int{
+		"id":           func(a, b provider.ProviderInformation) int { return compareInts(int(a.Id), int(b.Id)) },
+		"providerName": func(a, b provider.ProviderInformation) int { return strings.Compare(a.ProviderName, b.ProviderName) },
+	}, func(item provider.ProviderInformation) int { return int(item.Id) })
+}
+
+func (s *Store) GetProviderById(providerId int) (provider.ProviderInformation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range s.providers {
+		if int(item.Id) == providerId {
+			return item, nil
+		}
+	}
+
+	return provider.ProviderInformation{}, db.NotFound("provider", providerId)
+}
+
+func (s *Store) AddProvider(newProvider provider.ProviderInformation) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextProviderId
+	s.nextProviderId++
+
+	newProvider.Id = float64(id)
+	s.providers = append(s.providers, newProvider)
+
+	return id, nil
+}
+
+func (s *Store) UpdateProvider(providerId int, request provider.ProviderInformation) error {
+	if request.ProviderName == "" {
+		return db.Invalid("no fields to update for provider with id %d", providerId)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.providers {
+		if int(s.providers[i].Id) == providerId {
+			s.providers[i].ProviderName = request.ProviderName
+			return nil
+		}
+	}
+
+	return db.NotFound("provider", providerId)
+}
+
+// SetProviderInUse makes DeleteProvider without cascade fail with
+// db.ErrProviderInUse for the provider.
+func (s *Store) SetProviderInUse(providerId int, inUse bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.providersInUse[providerId] = inUse
+}
+
+func (s *Store) DeleteProvider(providerId int, cascade bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watched := func(a alert.PriceAlert) bool { return a.ProviderId == providerId }
+
+	if s.providersInUse[providerId] || s.anyAlert(watched) {
+		if !cascade {
+			return db.ErrProviderInUse
+		}
+		delete(s.providersInUse, providerId)
+		s.deleteAlerts(watched)
+	}
+
+	for i, item := range s.providers {
+		if int(item.Id) == providerId {
+			s.providers = append(s.providers[:i], s.providers[i+1:]...)
+			return nil
+		}
+	}
+
+	return db.NotFound("provider", providerId)
+}
+
+// ALERT methods
+
+func (s *Store) GetPriceAlerts(userId int) ([]alert.PriceAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alerts := make([]alert.PriceAlert, 0)
+	for _, a := range s.alerts {
+		if a.UserId == userId {
+			alerts = append(alerts, a)
+		}
+	}
+
+	return alerts, nil
+}
+
+// GetTriggeredAlerts returns no triggers, as there is no availability that
+// could cross an alert.
+func (s *Store) GetTriggeredAlerts(userId int) ([]alert.TriggeredAlert, error) {
+	return make([]alert.TriggeredAlert, 0), nil
+}
+
+func (s *Store) AddPriceAlert(newAlert alert.PriceAlert) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.hasUser(newAlert.UserId) {
+		return 0, db.NotFound("user", newAlert.UserId)
+	}
+
+	if err := s.checkAlertReferences(newAlert); err != nil {
+		return 0, err
+	}
+
+	newAlert.Id = s.nextAlertId
+	newAlert.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	s.nextAlertId++
+
+	s.alerts = append(s.alerts, newAlert)
+
+	return newAlert.Id, nil
+}
+
+func (s *Store) UpdatePriceAlert(userId int, alertId int, request alert.PriceAlertUpdate) error {
+	if request.SneakerId != nil {
+		return db.ErrAlertSneakerChanged
+	}
+
+	if request.ProviderId == nil && request.TargetPrice == nil {
+		return db.Invalid("no fields to update for alert with id %d", alertId)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if request.ProviderId != nil {
+		if err := s.checkAlertReferences(alert.PriceAlert{ProviderId: *request.ProviderId}); err != nil {
+			return err
+		}
+	}
+
+	for i := range s.alerts {
+		if s.alerts[i].Id != alertId || s.alerts[i].UserId != userId {
+			continue
+		}
+		if request.ProviderId != nil {
+			s.alerts[i].ProviderId = *request.ProviderId
+		}
+		if request.TargetPrice != nil {
+			s.alerts[i].TargetPrice = *request.TargetPrice
+		}
+		return nil
+	}
+
+	return db.NotFound("alert", alertId)
+}
+
+func (s *Store) DeletePriceAlert(userId int, alertId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.deleteAlerts(func(a alert.PriceAlert) bool { return a.Id == alertId && a.UserId == userId }) == 0 {
+		return db.NotFound("alert", alertId)
+	}
+
+	return nil
+}
+
+// checkAlertReferences mirrors db.DB: the sneaker and provider of an alert
+// must exist unless their id is zero.
+func (s *Store) checkAlertReferences(priceAlert alert.PriceAlert) error {
+	if priceAlert.SneakerId != 0 && !s.hasSneaker(priceAlert.SneakerId) {
+		return db.UnknownReference("sneakerId", "sneaker", priceAlert.SneakerId)
+	}
+
+	if priceAlert.ProviderId != 0 && !s.hasProvider(priceAlert.ProviderId) {
+		return db.UnknownReference("providerId", "provider", priceAlert.ProviderId)
+	}
+
+	return nil
+}
+
+func (s *Store) anyAlert(match func(alert.PriceAlert) bool) bool {
+	for _, a := range s.alerts {
+		if match(a) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deleteAlerts removes the matching alerts and returns how many there were.
+func (s *Store) deleteAlerts(match func(alert.PriceAlert) bool) int {
+	kept := s.alerts[:0]
+	for _, a := range s.alerts {
+		if !match(a) {
+			kept = append(kept, a)
+		}
+	}
+
+	deleted := len(s.alerts) - len(kept)
+	s.alerts = kept
+
+	return deleted
+}
+
+func (s *Store) hasUser(userId int) bool {
+	for _, u := range s.users {
+		if u.Id == userId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Store) hasSneaker(sneakerId int) bool {
+	for _, item := range s.sneakers {
+		if item.Id == sneakerId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Store) hasProvider(providerId int) bool {
+	for _, item := range s.providers {
+		if int(item.Id) == providerId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// REFRESH TOKEN methods
+
+func (s *Store) AddRefreshToken(token auth.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token.Id = len(s.refreshTokens) + 1
+	s.refreshTokens[token.Hash] = &storedRefreshToken{RefreshToken: token}
+
+	return nil
+}
+
+func (s *Store) UseRefreshToken(hash string) (auth.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.refreshTokens[hash]
+	if !ok || stored.revoked {
+		return auth.RefreshToken{}, db.ErrRefreshTokenInvalid
+	}
+
+	if stored.used {
+		s.revokeFamily(stored.FamilyId)
+		return auth.RefreshToken{}, db.ErrRefreshTokenReused
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return auth.RefreshToken{}, db.ErrRefreshTokenInvalid
+	}
+
+	stored.used = true
+
+	return stored.RefreshToken, nil
+}
+
+func (s *Store) RevokeRefreshTokenFamily(hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.refreshTokens[hash]
+	if !ok || !s.revokeFamily(stored.FamilyId) {
+		return db.ErrRefreshTokenInvalid
+	}
+
+	return nil
+}
+
+// revokeFamily reports whether any token was still active.
+func (s *Store) revokeFamily(familyId string) bool {
+	revoked := false
+	for _, token := range s.refreshTokens {
+		if token.FamilyId == familyId && !token.revoked {
+			token.revoked = true
+			revoked = true
+		}
+	}
+
+	return revoked
+}
+
+func (s *Store) revokeSubject(subjectId int, role string) {
+	for _, token := range s.refreshTokens {
+		if token.SubjectId == subjectId && token.Role == role {
+			token.revoked = true
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles holds the schema as numbered pairs of
+// <version>_<name>.up.sql and <version>_<name>.down.sql files.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt"`
+}
+
+// loadMigrations reads the embedded migrations sorted by version.
+func loadMigrations(files fs.FS) ([]migration, error) {
+	names, err := fs.Glob(files, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+
+	for _, name := range names {
+		base := path.Base(name)
+
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", base)
+		}
+
+		prefix, title, found := strings.Cut(strings.TrimSuffix(base, "."+direction+".sql"), "_")
+		if !found {
+			return nil, fmt.Errorf("migration %s: name must start with <version>_", base)
+		}
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", base, prefix)
+		}
+
+		contents, err := fs.ReadFile(files, name)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: title}
+			byVersion[version] = m
+		} else if m.Name != title {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, m.Name, title)
+		}
+
+		if direction == "up" {
+			m.Up = string(contents)
+		} else {
+			m.Down = string(contents)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func (d *DB) ensureMigrationsTable() error {
+	_, err := d.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+
+	return err
+}
+
+// MigrationStatus lists every known migration and when it was applied.
+func (d *DB) MigrationStatus() ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		entry := MigrationStatus{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := applied[m.Version]; ok {
+			entry.AppliedAt = &appliedAt
+		}
+		status = append(status, entry)
+	}
+
+	return status, nil
+}
+
+// MigrateUp applies every pending migration in version order, each in its
+// own transaction, and returns the versions it applied.
+func (d *DB) MigrateUp() ([]int, error) {
+	status, err := d.MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]int, 0)
+
+	for i, m := range migrations {
+		if status[i].AppliedAt != nil {
+			continue
+		}
+
+		if err := d.runMigration(migrationChecks[m.Version], m.Up, "INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name); err != nil {
+			return applied, fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+		}
+
+		applied = append(applied, m.Version)
+	}
+
+	return applied, nil
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first, and
+// returns the versions it reverted.
+func (d *DB) MigrateDown(steps int) ([]int, error) {
+	status, err := d.MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := make([]int, 0)
+
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := migrations[i]
+		if status[i].AppliedAt == nil {
+			continue
+		}
+
+		if m.Down == "" {
+			return reverted, fmt.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+
+		if err := d.runMigration(nil, m.Down, "DELETE FROM schema_migrations WHERE version = ?", m.Version); err != nil {
+			return reverted, fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+		}
+
+		reverted = append(reverted, m.Version)
+	}
+
+	return reverted, nil
+}
+
+// runMigration runs check, then executes script and the schema_migrations
+// bookkeeping statement in one transaction.
+func (d *DB) runMigration(check func(tx *sql.Tx) error, script string, record string, args ...interface{}) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if check != nil {
+		if err := check(tx); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(record, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// migrationChecks verify the data before an up migration that would fail on
+// it, so the error can say what has to be fixed.
+var migrationChecks = map[int]func(tx *sql.Tx) error{
+	8: checkUniqueEmails,
+}
+
+// checkUniqueEmails lists the emails used by more than one user or admin,
+// ignoring case.
+func checkUniqueEmails(tx *sql.Tx) error {
+	var duplicates []string
+
+	for _, table := range []string{"users", "admins"} {
+		rows, err := tx.Query("SELECT lower(email), group_concat(id, ', ') FROM " + table +
+			" GROUP BY lower(email) HAVING COUNT(*) > 1 ORDER BY lower(email)")
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var email, ids string
+			if err := rows.Scan(&email, &ids); err != nil {
+				rows.Close()
+				return err
+			}
+			duplicates = append(duplicates, fmt.Sprintf("%s %q (ids %s)", table, email, ids))
+		}
+
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	if len(duplicates) > 0 {
+		return fmt.Errorf("duplicate emails must be merged or removed first: %s", strings.Join(duplicates, "; "))
+	}
+
+	return nil
+}
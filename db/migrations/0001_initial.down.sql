@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS availability_scrappers;
+DROP TABLE IF EXISTS provider_information;
+DROP TABLE IF EXISTS product_providers;
+DROP TABLE IF EXISTS sneakers_information;
+DROP TABLE IF EXISTS sneakers;
+DROP TABLE IF EXISTS admins;
+DROP TABLE IF EXISTS users;
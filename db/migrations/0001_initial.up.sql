@@ -0,0 +1,53 @@
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	email TEXT,
+	password TEXT,
+	created_at DATETIME DEFAULT CURRENT_DATE
+);
+
+CREATE TABLE IF NOT EXISTS admins (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	email TEXT,
+	password TEXT
+);
+
+CREATE TABLE IF NOT EXISTS sneakers (
+	id INTEGER,
+	name TEXT,
+	model TEXT,
+	brand TEXT,
+	imageUrl TEXT,
+	CONSTRAINT SNEAKERS_PK PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS sneakers_information (
+	sneakerId INTEGER,
+	mainInfo TEXT,
+	mainImageUrl INTEGER,
+	additionalInfo INTEGER,
+	CONSTRAINT sneakers_information_FK FOREIGN KEY (sneakerId) REFERENCES sneakers(id)
+);
+
+CREATE TABLE IF NOT EXISTS product_providers (
+	id INTEGER PRIMARY KEY,
+	provider_name TEXT
+);
+
+CREATE TABLE IF NOT EXISTS provider_information (
+	id INTEGER PRIMARY KEY,
+	product_id INTEGER,
+	provider_id INTEGER,
+	price REAL,
+	available BOOLEAN,
+	FOREIGN KEY (product_id) REFERENCES sneakers(id),
+	FOREIGN KEY (provider_id) REFERENCES product_providers(id)
+);
+
+CREATE TABLE IF NOT EXISTS availability_scrappers (
+	id INTEGER PRIMARY KEY,
+	product_id INTEGER,
+	provider_id INTEGER,
+	search_for TEXT,
+	FOREIGN KEY (product_id) REFERENCES sneakers(id),
+	FOREIGN KEY (provider_id) REFERENCES product_providers(id)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS price_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	product_id INTEGER NOT NULL,
+	provider_id INTEGER NOT NULL,
+	price REAL,
+	available BOOLEAN,
+	recorded_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (product_id) REFERENCES sneakers(id),
+	FOREIGN KEY (provider_id) REFERENCES product_providers(id)
+);
+
+CREATE INDEX IF NOT EXISTS price_history_product_idx ON price_history (product_id, provider_id, recorded_at);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS price_alerts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	sneaker_id INTEGER NOT NULL,
+	provider_id INTEGER,
+	target_price REAL NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id),
+	FOREIGN KEY (sneaker_id) REFERENCES sneakers(id),
+	FOREIGN KEY (provider_id) REFERENCES product_providers(id)
+);
+
+CREATE INDEX IF NOT EXISTS price_alerts_sneaker_idx ON price_alerts (sneaker_id);
+
+CREATE TABLE IF NOT EXISTS triggered_alerts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	alert_id INTEGER NOT NULL,
+	provider_id INTEGER NOT NULL,
+	price REAL,
+	available BOOLEAN,
+	triggered_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (alert_id) REFERENCES price_alerts(id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS inbox_messages;
+DROP TABLE IF EXISTS notification_queue;
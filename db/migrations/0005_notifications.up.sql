@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS notification_queue (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	channel TEXT NOT NULL,
+	recipient TEXT NOT NULL DEFAULT '',
+	subject TEXT NOT NULL,
+	body TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	next_attempt_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	delivered_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS notification_queue_due_idx ON notification_queue (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS inbox_messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	subject TEXT NOT NULL,
+	body TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
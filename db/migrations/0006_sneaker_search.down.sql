@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS sneakers_information_fts_delete;
+DROP TRIGGER IF EXISTS sneakers_information_fts_update;
+DROP TRIGGER IF EXISTS sneakers_information_fts_insert;
+DROP TRIGGER IF EXISTS sneakers_fts_delete;
+DROP TRIGGER IF EXISTS sneakers_fts_update;
+DROP TRIGGER IF EXISTS sneakers_fts_insert;
+DROP TABLE IF EXISTS sneakers_fts;
@@ -0,0 +1,77 @@
+-- sneakers_fts indexes every sneaker by id. The information columns
+-- concatenate all sneakers_information rows of the sneaker.
+CREATE VIRTUAL TABLE IF NOT EXISTS sneakers_fts USING fts5(
+	name, model, brand, mainInfo, additionalInfo,
+	tokenize = 'unicode61 remove_diacritics 2',
+	prefix = '2 3'
+);
+
+CREATE TRIGGER IF NOT EXISTS sneakers_fts_insert AFTER INSERT ON sneakers BEGIN
+	DELETE FROM sneakers_fts WHERE rowid = new.id;
+	INSERT INTO sneakers_fts (rowid, name, model, brand, mainInfo, additionalInfo)
+	SELECT s.id, s.name, s.model, s.brand,
+		COALESCE((SELECT group_concat(mainInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), ''),
+		COALESCE((SELECT group_concat(additionalInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), '')
+	FROM sneakers s
+	WHERE s.id = new.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS sneakers_fts_update AFTER UPDATE ON sneakers BEGIN
+	DELETE FROM sneakers_fts WHERE rowid = old.id;
+	DELETE FROM sneakers_fts WHERE rowid = new.id;
+	INSERT INTO sneakers_fts (rowid, name, model, brand, mainInfo, additionalInfo)
+	SELECT s.id, s.name, s.model, s.brand,
+		COALESCE((SELECT group_concat(mainInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), ''),
+		COALESCE((SELECT group_concat(additionalInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), '')
+	FROM sneakers s
+	WHERE s.id = new.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS sneakers_fts_delete AFTER DELETE ON sneakers BEGIN
+	DELETE FROM sneakers_fts WHERE rowid = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS sneakers_information_fts_insert AFTER INSERT ON sneakers_information BEGIN
+	DELETE FROM sneakers_fts WHERE rowid = new.sneakerId;
+	INSERT INTO sneakers_fts (rowid, name, model, brand, mainInfo, additionalInfo)
+	SELECT s.id, s.name, s.model, s.brand,
+		COALESCE((SELECT group_concat(mainInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), ''),
+		COALESCE((SELECT group_concat(additionalInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), '')
+	FROM sneakers s
+	WHERE s.id = new.sneakerId;
+END;
+
+CREATE TRIGGER IF NOT EXISTS sneakers_information_fts_update AFTER UPDATE ON sneakers_information BEGIN
+	DELETE FROM sneakers_fts WHERE rowid = old.sneakerId;
+	INSERT INTO sneakers_fts (rowid, name, model, brand, mainInfo, additionalInfo)
+	SELECT s.id, s.name, s.model, s.brand,
+		COALESCE((SELECT group_concat(mainInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), ''),
+		COALESCE((SELECT group_concat(additionalInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), '')
+	FROM sneakers s
+	WHERE s.id = old.sneakerId;
+	DELETE FROM sneakers_fts WHERE rowid = new.sneakerId;
+	INSERT INTO sneakers_fts (rowid, name, model, brand, mainInfo, additionalInfo)
+	SELECT s.id, s.name, s.model, s.brand,
+		COALESCE((SELECT group_concat(mainInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), ''),
+		COALESCE((SELECT group_concat(additionalInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), '')
+	FROM sneakers s
+	WHERE s.id = new.sneakerId;
+END;
+
+CREATE TRIGGER IF NOT EXISTS sneakers_information_fts_delete AFTER DELETE ON sneakers_information BEGIN
+	DELETE FROM sneakers_fts WHERE rowid = old.sneakerId;
+	INSERT INTO sneakers_fts (rowid, name, model, brand, mainInfo, additionalInfo)
+	SELECT s.id, s.name, s.model, s.brand,
+		COALESCE((SELECT group_concat(mainInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), ''),
+		COALESCE((SELECT group_concat(additionalInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), '')
+	FROM sneakers s
+	WHERE s.id = old.sneakerId;
+END;
+
+-- Index sneakers that existed before the triggers
+INSERT INTO sneakers_fts (rowid, name, model, brand, mainInfo, additionalInfo)
+	SELECT s.id, s.name, s.model, s.brand,
+		COALESCE((SELECT group_concat(mainInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), ''),
+		COALESCE((SELECT group_concat(additionalInfo, ' ') FROM sneakers_information WHERE sneakerId = s.id), '')
+	FROM sneakers s
+	WHERE s.id NOT IN (SELECT rowid FROM sneakers_fts);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS provider_size_information (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	product_id INTEGER NOT NULL,
+	provider_id INTEGER NOT NULL,
+	size_us REAL NOT NULL,
+	price REAL,
+	available BOOLEAN,
+	FOREIGN KEY (product_id) REFERENCES sneakers(id),
+	FOREIGN KEY (provider_id) REFERENCES product_providers(id),
+	UNIQUE (product_id, provider_id, size_us)
+);
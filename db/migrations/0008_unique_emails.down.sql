@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS admins_email_unique_idx;
+DROP INDEX IF EXISTS users_email_unique_idx;
@@ -0,0 +1,4 @@
+-- Duplicate emails are reported by a check before this runs
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_unique_idx ON users (email COLLATE NOCASE);
+
+CREATE UNIQUE INDEX IF NOT EXISTS admins_email_unique_idx ON admins (email COLLATE NOCASE);
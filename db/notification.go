@@ -0,0 +1,179 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/Gretamass/kys-backend/alert"
+	"github.com/Gretamass/kys-backend/notify"
+	"time"
+)
+
+// SetNotificationChannels sets the channels a notification is queued on when
+// a price alert fires. It must be called before any availability is written.
+func (d *DB) SetNotificationChannels(channels []string) {
+	d.notificationChannels = channels
+}
+
+// GetDueNotifications returns pending notifications whose next attempt is due.
+func (d *DB) GetDueNotifications(now time.Time, limit int) ([]notify.Notification, error) {
+	query := `
+        SELECT id, user_id, channel, recipient, subject, body, status, attempts, last_error, next_attempt_at, created_at
+        FROM notification_queue
+        WHERE status = ? AND next_attempt_at <= ?
+        ORDER BY next_attempt_at, id
+        LIMIT ?;
+    `
+	return d.queryNotifications(query, notify.StatusPending, now.UTC().Format(sqliteTimeFormat), limit)
+}
+
+// GetDeadNotifications returns the notifications that ran out of attempts.
+func (d *DB) GetDeadNotifications() ([]notify.Notification, error) {
+	query := `
+        SELECT id, user_id, channel, recipient, subject, body, status, attempts, last_error, next_attempt_at, created_at
+        FROM notification_queue
+        WHERE status = ?
+        ORDER BY id DESC;
+    `
+	return d.queryNotifications(query, notify.StatusDead)
+}
+
+func (d *DB) queryNotifications(query string, args ...interface{}) ([]notify.Notification, error) {
+	rows, err := d.db.Query(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	notifications := make([]notify.Notification, 0)
+
+	for rows.Next() {
+		notification := notify.Notification{}
+		var lastError sql.NullString
+		err = rows.Scan(&notification.Id, &notification.UserId, &notification.Channel, &notification.Recipient,
+			&notification.Subject, &notification.Body, &notification.Status, &notification.Attempts, &lastError,
+			&notification.NextAttemptAt, &notification.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		notification.LastError = lastError.String
+		notifications = append(notifications, notification)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (d *DB) MarkNotificationDelivered(id int) error {
+	_, err := d.db.Exec("UPDATE notification_queue SET status = ?, attempts = attempts + 1, last_error = NULL, delivered_at = ? WHERE id = ?",
+		notify.StatusDelivered, time.Now().UTC().Format(sqliteTimeFormat), id)
+	return err
+}
+
+func (d *DB) MarkNotificationFailed(id int, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := notify.StatusPending
+	if dead {
+		status = notify.StatusDead
+	}
+
+	_, err := d.db.Exec("UPDATE notification_queue SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?",
+		status, lastError, nextAttemptAt.UTC().Format(sqliteTimeFormat), id)
+	return err
+}
+
+// RetryNotification moves a dead notification back to the queue with a fresh
+// set of attempts.
+func (d *DB) RetryNotification(id int) error {
+	result, err := d.db.Exec("UPDATE notification_queue SET status = ?, attempts = 0, next_attempt_at = ? WHERE id = ? AND status = ?",
+		notify.StatusPending, time.Now().UTC().Format(sqliteTimeFormat), id, notify.StatusDead)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return NotFound("dead notification", id)
+	}
+
+	return nil
+}
+
+func (d *DB) AddInboxMessage(message notify.InboxMessage) error {
+	_, err := d.db.Exec("INSERT INTO inbox_messages (user_id, subject, body) VALUES (?, ?, ?)",
+		message.UserId, message.Subject, message.Body)
+	return err
+}
+
+func (d *DB) GetInboxMessages(userId int) ([]notify.InboxMessage, error) {
+	rows, err := d.db.Query("SELECT id, user_id, subject, body, created_at FROM inbox_messages WHERE user_id = ? ORDER BY id DESC", userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	messages := make([]notify.InboxMessage, 0)
+
+	for rows.Next() {
+		message := notify.InboxMessage{}
+		err = rows.Scan(&message.Id, &message.UserId, &message.Subject, &message.Body, &message.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// enqueueAlertNotifications queues a notification about a triggered alert on
+// every configured channel.
+func (d *DB) enqueueAlertNotifications(tx *sql.Tx, triggered alert.TriggeredAlert) error {
+	if len(d.notificationChannels) == 0 {
+		return nil
+	}
+
+	var sneakerName, providerName, email sql.NullString
+	err := tx.QueryRow("SELECT (SELECT name FROM sneakers WHERE id = ?), (SELECT provider_name FROM product_providers WHERE id = ?), (SELECT email FROM users WHERE id = ?)",
+		triggered.SneakerId, triggered.ProviderId, triggered.UserId).Scan(&sneakerName, &providerName, &email)
+	if err != nil {
+		return err
+	}
+
+	subject, body := notify.AlertNotification(triggered, sneakerName.String, providerName.String)
+
+	for _, channel := range d.notificationChannels {
+		recipient := ""
+		if channel == notify.ChannelEmail {
+			recipient = email.String
+		}
+
+		_, err := tx.Exec("INSERT INTO notification_queue (user_id, channel, recipient, subject, body, status, next_attempt_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			triggered.UserId, channel, recipient, subject, body, notify.StatusPending, time.Now().UTC().Format(sqliteTimeFormat))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/Gretamass/kys-backend/sneaker"
+)
+
+// GetSneakerOffers returns the available offers of a sneaker ranked by price.
+func (d *DB) GetSneakerOffers(sneakerId int) (sneaker.SneakerOffers, error) {
+	singleSneaker, err := d.GetSneakerById(sneakerId)
+	if err != nil {
+		return sneaker.SneakerOffers{}, err
+	}
+
+	offers, err := d.queryOffers("WHERE s.id = ?", sneakerId)
+	if err != nil {
+		return sneaker.SneakerOffers{}, err
+	}
+
+	if len(offers) == 1 {
+		return offers[0], nil
+	}
+
+	// The sneaker exists but nothing is available right now
+	return sneaker.SneakerOffers{
+		Id:       singleSneaker.Id,
+		Name:     singleSneaker.Name,
+		Model:    singleSneaker.Model,
+		Brand:    singleSneaker.Brand,
+		ImageUrl: singleSneaker.ImageUrl,
+		Offers:   []sneaker.Offer{},
+	}, nil
+}
+
+// GetBestOffers returns every sneaker that has at least one available offer,
+// with its offers ranked by price.
+func (d *DB) GetBestOffers() ([]sneaker.SneakerOffers, error) {
+	return d.queryOffers("")
+}
+
+func (d *DB) queryOffers(where string, args ...interface{}) ([]sneaker.SneakerOffers, error) {
+	query := `
+        SELECT s.id, s.name, s.model, s.brand, s.imageUrl, pi.provider_id, pp.provider_name, pi.price, pi.available
+        FROM sneakers s
+        JOIN provider_information pi ON s.id = pi.product_id
+        LEFT JOIN product_providers pp ON pp.id = pi.provider_id
+        ` + where + `
+        ORDER BY s.id;
+    `
+	rows, err := d.db.Query(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	sneakers := make([]sneaker.SneakerOffers, 0)
+
+	for rows.Next() {
+		singleSneaker := sneaker.SneakerOffers{}
+		offer := sneaker.Offer{}
+		var providerName sql.NullString
+		var available bool
+		err = rows.Scan(&singleSneaker.Id, &singleSneaker.Name, &singleSneaker.Model, &singleSneaker.Brand, &singleSneaker.ImageUrl,
+			&offer.ProviderId, &providerName, &offer.Price, &available)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// available holds both booleans and 'true'/'false' strings, so it is
+		// filtered here rather than in SQL
+		if !available {
+			continue
+		}
+
+		offer.ProviderName = providerName.String
+
+		// Rows are ordered by sneaker, so a new id starts a new entry
+		if len(sneakers) == 0 || sneakers[len(sneakers)-1].Id != singleSneaker.Id {
+			sneakers = append(sneakers, singleSneaker)
+		}
+
+		last := &sneakers[len(sneakers)-1]
+		last.Offers = append(last.Offers, offer)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sneakers {
+		sneakers[i].Rank()
+	}
+
+	return sneakers, nil
+}
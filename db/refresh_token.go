@@ -0,0 +1,130 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/Gretamass/kys-backend/auth"
+	"time"
+)
+
+var (
+	// ErrRefreshTokenInvalid is returned for unknown, expired or revoked refresh tokens.
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token
+	// is presented again. The whole token family is revoked when it happens.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+func (d *DB) AddRefreshToken(token auth.RefreshToken) error {
+	row, err := d.db.Prepare("INSERT INTO refresh_tokens (token_hash, family_id, subject_id, role, expires_at) VALUES (?, ?, ?, ?, ?)")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = row.Exec(token.Hash, token.FamilyId, token.SubjectId, token.Role, token.ExpiresAt.UTC())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UseRefreshToken marks the refresh token with the given hash as used and
+// returns it so a successor can be issued in the same family. Presenting a
+// token that was already used revokes its family and returns
+// ErrRefreshTokenReused.
+func (d *DB) UseRefreshToken(hash string) (auth.RefreshToken, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return auth.RefreshToken{}, err
+	}
+
+	defer tx.Rollback()
+
+	token := auth.RefreshToken{}
+	var usedAt, revokedAt sql.NullTime
+
+	err = tx.QueryRow("SELECT id, token_hash, family_id, subject_id, role, expires_at, used_at, revoked_at FROM refresh_tokens WHERE token_hash = ?", hash).
+		Scan(&token.Id, &token.Hash, &token.FamilyId, &token.SubjectId, &token.Role, &token.ExpiresAt, &usedAt, &revokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return auth.RefreshToken{}, ErrRefreshTokenInvalid
+		}
+		return auth.RefreshToken{}, err
+	}
+
+	if revokedAt.Valid {
+		return auth.RefreshToken{}, ErrRefreshTokenInvalid
+	}
+
+	now := time.Now().UTC()
+
+	if !usedAt.Valid {
+		if now.After(token.ExpiresAt) {
+			return auth.RefreshToken{}, ErrRefreshTokenInvalid
+		}
+
+		result, err := tx.Exec("UPDATE refresh_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL", now, token.Id)
+		if err != nil {
+			return auth.RefreshToken{}, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return auth.RefreshToken{}, err
+		}
+
+		// A concurrent request may have used the token since it was read
+		if rowsAffected == 1 {
+			if err := tx.Commit(); err != nil {
+				return auth.RefreshToken{}, err
+			}
+
+			return token, nil
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL", now, token.FamilyId); err != nil {
+		return auth.RefreshToken{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return auth.RefreshToken{}, err
+	}
+
+	return auth.RefreshToken{}, ErrRefreshTokenReused
+}
+
+// RevokeRefreshTokenFamily revokes every token descending from the same login
+// as the token with the given hash.
+func (d *DB) RevokeRefreshTokenFamily(hash string) error {
+	query := `
+        UPDATE refresh_tokens
+        SET revoked_at = ?
+        WHERE revoked_at IS NULL
+          AND family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = ?);
+    `
+	result, err := d.db.Exec(query, time.Now().UTC(), hash)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRefreshTokenInvalid
+	}
+
+	return nil
+}
+
+// revokeSubjectRefreshTokens revokes every refresh token issued to the
+// subject so a deleted user or admin cannot refresh any more.
+func revokeSubjectRefreshTokens(tx *sql.Tx, subjectId int, role string) error {
+	_, err := tx.Exec("UPDATE refresh_tokens SET revoked_at = ? WHERE subject_id = ? AND role = ? AND revoked_at IS NULL", time.Now().UTC(), subjectId, role)
+	return err
+}
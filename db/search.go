@@ -0,0 +1,100 @@
+package db
+
+import (
+	"github.com/Gretamass/kys-backend/sneaker"
+	"strings"
+	"unicode"
+)
+
+// ftsQuery turns free text such as "jordan 1 chic" into an FTS5 query
+// requiring every term. The last term is matched as a prefix so partially
+// typed words still match. It returns "" when q has no terms.
+func ftsQuery(q string) string {
+	terms := strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, term := range terms {
+		terms[i] = `"` + term + `"`
+	}
+
+	// Matching the exact word as well lets bm25 rank whole-word hits first
+	if last := len(terms) - 1; last >= 0 {
+		terms[last] = "(" + terms[last] + " OR " + terms[last] + "*)"
+	}
+
+	return strings.Join(terms, " AND ")
+}
+
+var searchSortColumns = map[string]string{
+	"rank":  "rank",
+	"id":    "s.id",
+	"name":  "s.name",
+	"model": "s.model",
+	"brand": "s.brand",
+}
+
+// SearchSneakers runs a full-text search over sneaker names, models, brands
+// and information. Results are ranked with bm25, weighting name matches
+// highest, and carry snippets with matches wrapped in <mark> tags. They are
+// ordered by rank unless opts.Sort names another field.
+func (d *DB) SearchSneakers(q string, opts ListOptions) ([]sneaker.SearchResult, int, error) {
+	order := " ORDER BY rank, s.id"
+	if opts.Sort != "" {
+		var err error
+		if order, err = (&listQuery{}).orderClause(opts, searchSortColumns, "s.id"); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	match := ftsQuery(q)
+	if match == "" {
+		return []sneaker.SearchResult{}, 0, nil
+	}
+
+	var total int
+	err := d.db.QueryRow("SELECT COUNT(*) FROM sneakers_fts WHERE sneakers_fts MATCH ?", match).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+        SELECT s.id, s.name, s.model, s.brand, s.imageUrl,
+               bm25(sneakers_fts, 20.0, 10.0, 5.0, 0.5, 0.5) AS rank,
+               highlight(sneakers_fts, 0, '<mark>', '</mark>'),
+               snippet(sneakers_fts, -1, '<mark>', '</mark>', '…', 16)
+        FROM sneakers_fts
+        JOIN sneakers s ON s.id = sneakers_fts.rowid
+        WHERE sneakers_fts MATCH ?` + order + `
+        LIMIT ? OFFSET ?;
+    `
+	rows, err := d.db.Query(query, match, opts.Limit, opts.Offset)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	results := make([]sneaker.SearchResult, 0)
+
+	for rows.Next() {
+		result := sneaker.SearchResult{}
+		err = rows.Scan(&result.Id, &result.Name, &result.Model, &result.Brand, &result.ImageUrl,
+			&result.Rank, &result.HighlightedName, &result.Snippet)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		results = append(results, result)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"github.com/Gretamass/kys-backend/alert"
+	"github.com/Gretamass/kys-backend/auth"
+	"github.com/Gretamass/kys-backend/notify"
+	"github.com/Gretamass/kys-backend/provider"
+	"github.com/Gretamass/kys-backend/sneaker"
+	"github.com/Gretamass/kys-backend/user"
+	"time"
+)
+
+// The store interfaces are what the HTTP handlers need from the database.
+// DB implements all of them and the memory package provides an in-memory
+// implementation of all but NotificationStore and CatalogStore for tests.
+
+type UserStore interface {
+	GetUsers(opts ListOptions) ([]user.User, int, error)
+	GetUserById(userId int) (user.User, error)
+	AddUser(newUser user.User) error
+	UpdateUser(userId int, request user.User) error
+	DeleteUser(userId int) error
+	LoginUser(request user.User) (user.User, bool, error)
+}
+
+type AdminStore interface {
+	GetAdmins() ([]user.Admin, error)
+	GetAdminById(adminId int) (user.Admin, error)
+	AddAdmin(admin user.Admin) error
+	UpdateAdmin(adminId int, request user.Admin) error
+	DeleteAdmin(adminId int) error
+	LoginAdmin(request user.Admin) (user.Admin, bool, error)
+}
+
+type SneakerStore interface {
+	GetSneakers(opts ListOptions) ([]sneaker.Sneaker, int, error)
+	AddSneaker(sneaker sneaker.Sneaker) (int, error)
+	UpdateSneaker(sneakerId int, request sneaker.Sneaker) error
+	DeleteSneaker(sneakerId int) error
+	GetSneakersInfo(opts ListOptions) ([]sneaker.SneakerInformation, int, error)
+	GetSneakerInfo(sneakerId int) (*sneaker.SneakerInformation, error)
+}
+
+type ProviderStore interface {
+	GetProviders(opts ListOptions) ([]provider.ProviderInformation, int, error)
+	GetProviderById(providerId int) (provider.ProviderInformation, error)
+	AddProvider(provider provider.ProviderInformation) (int, error)
+	UpdateProvider(providerId int, request provider.ProviderInformation) error
+	DeleteProvider(providerId int, cascade bool) error
+}
+
+type RefreshTokenStore interface {
+	AddRefreshToken(token auth.RefreshToken) error
+	UseRefreshToken(hash string) (auth.RefreshToken, error)
+	RevokeRefreshTokenFamily(hash string) error
+}
+
+type AlertStore interface {
+	GetPriceAlerts(userId int) ([]alert.PriceAlert, error)
+	GetTriggeredAlerts(userId int) ([]alert.TriggeredAlert, error)
+	AddPriceAlert(newAlert alert.PriceAlert) (int, error)
+	UpdatePriceAlert(userId int, alertId int, request alert.PriceAlertUpdate) error
+	DeletePriceAlert(userId int, alertId int) error
+}
+
+// NotificationStore covers the inbox and the dead letters of the delivery
+// queue.
+type NotificationStore interface {
+	GetInboxMessages(userId int) ([]notify.InboxMessage, error)
+	GetDeadNotifications() ([]notify.Notification, error)
+	RetryNotification(id int) error
+}
+
+// CatalogStore covers search and the scraped availability, offers, scrapper
+// rows and price history of sneakers.
+type CatalogStore interface {
+	SearchSneakers(q string, opts ListOptions) ([]sneaker.SearchResult, int, error)
+	GetSneakersAvailability(sizeUS float64) ([]sneaker.SneakerAvailability, error)
+	GetSneakerOffers(sneakerId int) (sneaker.SneakerOffers, error)
+	GetBestOffers() ([]sneaker.SneakerOffers, error)
+	GetSneakerScrapper(sneakerId int) ([]sneaker.AvailabilityScrappers, error)
+	GetPriceHistory(sneakerId int, from time.Time, to time.Time) ([]sneaker.PriceHistory, error)
+}
+
+var (
+	_ UserStore         = (*DB)(nil)
+	_ AdminStore        = (*DB)(nil)
+	_ SneakerStore      = (*DB)(nil)
+	_ ProviderStore     = (*DB)(nil)
+	_ RefreshTokenStore = (*DB)(nil)
+	_ AlertStore        = (*DB)(nil)
+	_ NotificationStore = (*DB)(nil)
+	_ CatalogStore      = (*DB)(nil)
+)
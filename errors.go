@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/Gretamass/kys-backend/db"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const requestIdHeader = "X-Request-ID"
+
+// errorCodes names the statuses in the error envelope.
+var errorCodes = map[int]string{
+	http.StatusBadRequest:          "bad_request",
+	http.StatusUnauthorized:        "unauthorized",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not_found",
+	http.StatusConflict:            "conflict",
+	http.StatusUnprocessableEntity: "validation_failed",
+	http.StatusInternalServerError: "internal_error",
+	http.StatusNotImplemented:      "not_implemented",
+}
+
+// requestId reuses the X-Request-ID sent by the client when it is a valid
+// id or generates one, and echoes it in the response.
+func requestId() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIdHeader)
+		if !validRequestId(id) {
+			id = newRequestId()
+		}
+
+		c.Set(requestIdContextKey, id)
+		c.Header(requestIdHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestId accepts up to 64 letters, digits, dots, underscores and
+// dashes so client ids cannot smuggle anything into headers or logs.
+func validRequestId(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestId() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		// still unique enough to correlate log lines
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+// respondError aborts the request with the error envelope shared by all
+// failed requests.
+func respondError(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, errorBody(c, status, message))
+}
+
+func errorBody(c *gin.Context, status int, message string) gin.H {
+	return gin.H{
+		"error":     message,
+		"code":      errorCodes[status],
+		"requestId": c.GetString(requestIdContextKey),
+	}
+}
+
+// errorStatus maps the error kinds of the db package to a status.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, db.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, db.ErrInvalidSort):
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
+// errorHandler answers requests whose handler attached an error with
+// c.Error instead of writing a response. Unknown errors are reported as
+// internal errors without details; requestLogger logs the cause.
+func errorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		status := errorStatus(err)
+
+		message := err.Error()
+		if status == http.StatusInternalServerError {
+			message = "internal error"
+		}
+
+		body := errorBody(c, status, message)
+
+		// name the field behind errors such as a taken email or an unknown
+		// sneaker id
+		var dbErr *db.Error
+		if errors.As(err, &dbErr) && dbErr.Field != "" {
+			body["field"] = dbErr.Field
+		}
+
+		c.AbortWithStatusJSON(status, body)
+	}
+}
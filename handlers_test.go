@@ -0,0 +1,522 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Gretamass/kys-backend/auth"
+	"github.com/Gretamass/kys-backend/config"
+	"github.com/Gretamass/kys-backend/db"
+	"github.com/Gretamass/kys-backend/db/memory"
+	"github.com/Gretamass/kys-backend/metrics"
+	"github.com/Gretamass/kys-backend/user"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if err := registerValidations(); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// testAPI drives the router backed by the in-memory store.
+type testAPI struct {
+	t       *testing.T
+	store   *memory.Store
+	srv     *server
+	handler http.Handler
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+
+	store := memory.New()
+	srv := &server{
+		users:     store,
+		admins:    store,
+		sneakers:  store,
+		providers: store,
+		sessions:  store,
+		alerts:    store,
+		tokens:    auth.NewTokenManager("test-secret", "kys-backend", time.Minute, time.Hour),
+	}
+
+	r, err := srv.router(config.Default().Server, metrics.New())
+	if err != nil {
+		t.Fatalf("router: %v", err)
+	}
+
+	return &testAPI{t: t, store: store, srv: srv, handler: r}
+}
+
+type response struct {
+	status int
+	body   map[string]interface{}
+}
+
+// do sends body as JSON, with token as bearer token when it is set.
+func (a *testAPI) do(method string, path string, body interface{}, token string) response {
+	a.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			a.t.Fatalf("marshal body: %v", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	request := httptest.NewRequest(method, path, reader)
+	request.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	recorder := httptest.NewRecorder()
+	a.handler.ServeHTTP(recorder, request)
+
+	result := response{status: recorder.Code}
+	if recorder.Body.Len() > 0 {
+		if err := json.Unmarshal(recorder.Body.Bytes(), &result.body); err != nil {
+			a.t.Fatalf("%s %s: decode %q: %v", method, path, recorder.Body.String(), err)
+		}
+	}
+
+	return result
+}
+
+// expect fails the test unless the response has the given status.
+func (a *testAPI) expect(r response, status int, what string) response {
+	a.t.Helper()
+
+	if r.status != status {
+		a.t.Fatalf("%s: status = %d, want %d, body %v", what, r.status, status, r.body)
+	}
+
+	return r
+}
+
+// expectError additionally checks the code of the error envelope.
+func (a *testAPI) expectError(r response, status int, code string, what string) response {
+	a.t.Helper()
+
+	a.expect(r, status, what)
+	if r.body["code"] != code || r.body["requestId"] == "" {
+		a.t.Fatalf("%s: body = %v, want code %q and a request id", what, r.body, code)
+	}
+
+	return r
+}
+
+func (a *testAPI) adminToken() string {
+	a.t.Helper()
+
+	if err := a.store.AddAdmin(user.Admin{Email: "admin@kys.test", Password: "adminpass1"}); err != nil {
+		a.t.Fatalf("add admin: %v", err)
+	}
+
+	admins, _ := a.store.GetAdmins()
+	return a.token(admins[len(admins)-1].Id, auth.RoleAdmin)
+}
+
+// userToken creates a user and returns its id and an access token.
+func (a *testAPI) userToken(email string) (int, string) {
+	a.t.Helper()
+
+	if err := a.store.AddUser(user.User{Email: email, Password: "userpass1"}); err != nil {
+		a.t.Fatalf("add user: %v", err)
+	}
+
+	users, _, _ := a.store.GetUsers(db.ListOptions{Filters: map[string]string{"email": email}})
+	return users[0].Id, a.token(users[0].Id, auth.RoleUser)
+}
+
+func (a *testAPI) token(id int, role string) string {
+	a.t.Helper()
+
+	token, err := a.srv.tokens.Issue(id, role)
+	if err != nil {
+		a.t.Fatalf("issue token: %v", err)
+	}
+
+	return token
+}
+
+func data(r response) map[string]interface{} {
+	value, _ := r.body["data"].(map[string]interface{})
+	return value
+}
+
+func list(r response) []interface{} {
+	value, _ := r.body["data"].([]interface{})
+	return value
+}
+
+func TestUserHandlers(t *testing.T) {
+	api := newTestAPI(t)
+	admin := api.adminToken()
+
+	api.expect(api.do("POST", "/user/", gin.H{"email": "greta@test.com", "password": "sneakers1"}, ""), 200, "create user")
+
+	conflict := api.expectError(api.do("POST", "/user/", gin.H{"email": "GRETA@test.com", "password": "sneakers1"}, ""),
+		409, "conflict", "create user with taken email")
+	if conflict.body["field"] != "email" {
+		t.Errorf("conflict field = %v, want email", conflict.body["field"])
+	}
+
+	invalid := api.expectError(api.do("POST", "/user/", gin.H{"email": "not-an-email", "password": "short"}, ""),
+		422, "validation_failed", "create invalid user")
+	if fields, _ := invalid.body["fields"].([]interface{}); len(fields) != 2 {
+		t.Errorf("fields = %v, want email and password", invalid.body["fields"])
+	}
+
+	api.expectError(api.do("GET", "/user/", nil, ""), 401, "unauthorized", "list users without token")
+
+	users := api.expect(api.do("GET", "/user/?sort=email", nil, admin), 200, "list users")
+	if len(list(users)) != 1 {
+		t.Fatalf("users = %v, want one user", users.body)
+	}
+
+	api.expectError(api.do("GET", "/user/?sort=password", nil, admin), 400, "bad_request", "sort users by unknown field")
+
+	id, own := api.userToken("aivaras@test.com")
+	_, other := api.userToken("someone@test.com")
+
+	api.expect(api.do("GET", "/user/"+strconv.Itoa(id), nil, own), 200, "get own user")
+	api.expectError(api.do("GET", "/user/"+strconv.Itoa(id), nil, other), 403, "forbidden", "get another user")
+	api.expectError(api.do("GET", "/user/", nil, own), 403, "forbidden", "list users as user")
+	api.expectError(api.do("GET", "/user/abc", nil, admin), 400, "bad_request", "get user with bad id")
+	api.expectError(api.do("GET", "/user/999", nil, admin), 404, "not_found", "get missing user")
+
+	api.expect(api.do("PATCH", "/user/"+strconv.Itoa(id), gin.H{"email": "aivaras@kys.test"}, own), 200, "update own email")
+	api.expectError(api.do("PATCH", "/user/"+strconv.Itoa(id), gin.H{"email": "greta@test.com"}, own), 409, "conflict", "update to a taken email")
+	api.expectError(api.do("PATCH", "/user/"+strconv.Itoa(id), gin.H{"password": "nodigits"}, own), 422, "validation_failed", "update to a weak password")
+
+	// 41 characters but 81 bytes, more than bcrypt can hash
+	multibyte := strings.Repeat("é", 40) + "1"
+	api.expectError(api.do("PATCH", "/user/"+strconv.Itoa(id), gin.H{"password": multibyte}, own), 422, "validation_failed", "update to a password over 72 bytes")
+	api.expectError(api.do("POST", "/user/", gin.H{"email": "long@test.com", "password": multibyte}, ""), 422, "validation_failed", "create user with a password over 72 bytes")
+	api.expectError(api.do("PATCH", "/user/"+strconv.Itoa(id), gin.H{}, own), 422, "validation_failed", "update without fields")
+
+	updated := api.expect(api.do("GET", "/user/"+strconv.Itoa(id), nil, own), 200, "get updated user")
+	if data(updated)["email"] != "aivaras@kys.test" {
+		t.Errorf("email = %v, want aivaras@kys.test", data(updated)["email"])
+	}
+
+	api.expectError(api.do("DELETE", "/user/"+strconv.Itoa(id), nil, own), 403, "forbidden", "delete user as user")
+	api.expect(api.do("DELETE", "/user/"+strconv.Itoa(id), nil, admin), 200, "delete user")
+	api.expectError(api.do("DELETE", "/user/"+strconv.Itoa(id), nil, admin), 404, "not_found", "delete deleted user")
+
+	// the token outlives its user
+	api.expectError(api.do("GET", "/login/me", nil, own), 401, "unauthorized", "use token of deleted user")
+}
+
+func TestLoginAndRefresh(t *testing.T) {
+	api := newTestAPI(t)
+	admin := api.adminToken()
+
+	api.expect(api.do("POST", "/user/", gin.H{"email": "greta@test.com", "password": "sneakers1"}, ""), 200, "create user")
+
+	api.expectError(api.do("POST", "/login/", gin.H{"email": "greta@test.com", "password": "wrong1234"}, ""),
+		401, "unauthorized", "login with wrong password")
+	api.expectError(api.do("POST", "/login/", gin.H{"email": "greta@test.com"}, ""),
+		422, "validation_failed", "login without password")
+
+	login := api.expect(api.do("POST", "/login/", gin.H{"email": "Greta@Test.com", "password": "sneakers1"}, ""), 200, "login")
+	token, _ := login.body["token"].(string)
+	refresh, _ := login.body["refreshToken"].(string)
+
+	me := api.expect(api.do("GET", "/login/me", nil, token), 200, "current user")
+	if me.body["role"] != auth.RoleUser || data(me)["email"] != "greta@test.com" {
+		t.Errorf("me = %v, want greta as user", me.body)
+	}
+
+	rotated := api.expect(api.do("POST", "/login/refresh", gin.H{"refreshToken": refresh}, ""), 200, "refresh")
+	next, _ := rotated.body["refreshToken"].(string)
+	if next == "" || next == refresh {
+		t.Fatalf("refresh token was not rotated: %v", rotated.body)
+	}
+
+	// presenting a rotated token again revokes the whole family
+	api.expectError(api.do("POST", "/login/refresh", gin.H{"refreshToken": refresh}, ""), 401, "unauthorized", "reuse refresh token")
+	api.expectError(api.do("POST", "/login/refresh", gin.H{"refreshToken": next}, ""), 401, "unauthorized", "refresh revoked family")
+
+	login = api.expect(api.do("POST", "/login/", gin.H{"email": "greta@test.com", "password": "sneakers1"}, ""), 200, "login again")
+	refresh, _ = login.body["refreshToken"].(string)
+
+	api.expect(api.do("POST", "/login/logout", gin.H{"refreshToken": refresh}, ""), 200, "logout")
+	api.expectError(api.do("POST", "/login/refresh", gin.H{"refreshToken": refresh}, ""), 401, "unauthorized", "refresh after logout")
+
+	login = api.expect(api.do("POST", "/login/", gin.H{"email": "greta@test.com", "password": "sneakers1"}, ""), 200, "login before delete")
+	refresh, _ = login.body["refreshToken"].(string)
+	id := int(data(login)["id"].(float64))
+
+	api.expect(api.do("DELETE", "/user/"+strconv.Itoa(id), nil, admin), 200, "delete user")
+	api.expectError(api.do("POST", "/login/refresh", gin.H{"refreshToken": refresh}, ""), 401, "unauthorized", "refresh deleted user")
+
+	api.expectError(api.do("POST", "/login/admin", gin.H{"email": "admin@kys.test", "password": "wrong1234"}, ""),
+		401, "unauthorized", "admin login with wrong password")
+	adminLogin := api.expect(api.do("POST", "/login/admin", gin.H{"email": "admin@kys.test", "password": "adminpass1"}, ""), 200, "admin login")
+	adminToken, _ := adminLogin.body["token"].(string)
+
+	if me := api.expect(api.do("GET", "/login/me", nil, adminToken), 200, "current admin"); me.body["role"] != auth.RoleAdmin {
+		t.Errorf("me = %v, want admin role", me.body)
+	}
+}
+
+func TestAdminHandlers(t *testing.T) {
+	api := newTestAPI(t)
+	admin := api.adminToken()
+	_, userToken := api.userToken("greta@test.com")
+
+	api.expectError(api.do("GET", "/admin/", nil, ""), 401, "unauthorized", "list admins without token")
+	api.expectError(api.do("GET", "/admin/", nil, "not-a-token"), 401, "unauthorized", "list admins with bad token")
+	api.expectError(api.do("GET", "/admin/", nil, userToken), 403, "forbidden", "list admins as user")
+
+	api.expect(api.do("POST", "/admin/", gin.H{"email": "second@kys.test", "password": "adminpass2"}, admin), 200, "create admin")
+	api.expectError(api.do("POST", "/admin/", gin.H{"email": "Second@kys.test", "password": "adminpass2"}, admin), 409, "conflict", "create admin with taken email")
+	api.expectError(api.do("POST", "/admin/", gin.H{"email": "third@kys.test"}, admin), 422, "validation_failed", "create admin without password")
+
+	admins := api.expect(api.do("GET", "/admin/", nil, admin), 200, "list admins")
+	if len(list(admins)) != 2 {
+		t.Fatalf("admins = %v, want two admins", admins.body)
+	}
+
+	second := list(admins)[1].(map[string]interface{})
+	id := strconv.Itoa(int(second["id"].(float64)))
+
+	if _, ok := second["password"]; ok {
+		t.Errorf("admin %v exposes its password", second)
+	}
+
+	api.expect(api.do("GET", "/admin/"+id, nil, admin), 200, "get admin")
+	api.expect(api.do("PATCH", "/admin/"+id, gin.H{"email": "renamed@kys.test"}, admin), 200, "update admin")
+	api.expectError(api.do("PATCH", "/admin/"+id, gin.H{"email": "admin@kys.test"}, admin), 409, "conflict", "update admin to a taken email")
+	api.expectError(api.do("PATCH", "/admin/999", gin.H{"email": "missing@kys.test"}, admin), 404, "not_found", "update missing admin")
+
+	api.expect(api.do("DELETE", "/admin/"+id, nil, admin), 200, "delete admin")
+	api.expectError(api.do("GET", "/admin/"+id, nil, admin), 404, "not_found", "get deleted admin")
+}
+
+func TestSneakerHandlers(t *testing.T) {
+	api := newTestAPI(t)
+	admin := api.adminToken()
+	_, userToken := api.userToken("greta@test.com")
+
+	sneaker := gin.H{"name": "Air Jordan 1 High", "model": "Air Jordan 1", "brand": "Air Jordan", "imageUrl": "https://img.kys.test/aj1.png"}
+
+	api.expectError(api.do("POST", "/sneaker/", sneaker, userToken), 403, "forbidden", "create sneaker as user")
+
+	created := api.expect(api.do("POST", "/sneaker/", sneaker, admin), 200, "create sneaker")
+	id := strconv.Itoa(int(data(created)["id"].(float64)))
+
+	api.expect(api.do("POST", "/sneaker/", gin.H{"name": "Dunk Low", "model": "Dunk", "brand": "Nike"}, admin), 200, "create second sneaker")
+
+	invalid := api.expectError(api.do("POST", "/sneaker/", gin.H{"name": "No brand", "imageUrl": "not a url"}, admin),
+		422, "validation_failed", "create invalid sneaker")
+	if fields, _ := invalid.body["fields"].([]interface{}); len(fields) != 3 {
+		t.Errorf("fields = %v, want model, brand and imageUrl", invalid.body["fields"])
+	}
+
+	api.expectError(api.do("POST", "/sneaker/", gin.H{"name": 1}, admin), 422, "validation_failed", "create sneaker with wrong type")
+
+	// a new sneaker has no information yet but can be read back
+	info := api.expect(api.do("GET", "/sneaker/"+id, nil, ""), 200, "get sneaker")
+	if data(info)["name"] != "Air Jordan 1 High" {
+		t.Errorf("sneaker = %v", info.body)
+	}
+
+	sorted := api.expect(api.do("GET", "/sneaker/?sort=-name&limit=1", nil, ""), 200, "list sneakers")
+	if items := list(sorted); len(items) != 1 || items[0].(map[string]interface{})["name"] != "Dunk Low" {
+		t.Errorf("sneakers = %v, want Dunk Low first", sorted.body)
+	}
+	if meta, _ := sorted.body["meta"].(map[string]interface{}); meta["total"] != float64(2) || meta["nextCursor"] != "1" {
+		t.Errorf("meta = %v, want total 2 and next cursor 1", sorted.body["meta"])
+	}
+
+	filtered := api.expect(api.do("GET", "/sneaker/?brand=nike", nil, ""), 200, "filter sneakers")
+	if len(list(filtered)) != 1 {
+		t.Errorf("sneakers = %v, want the Nike sneaker only", filtered.body)
+	}
+
+	api.expectError(api.do("GET", "/sneaker/?sort=price", nil, ""), 400, "bad_request", "sort sneakers by unknown field")
+	api.expectError(api.do("GET", "/sneaker/?limit=0", nil, ""), 400, "bad_request", "list sneakers with bad limit")
+
+	api.expect(api.do("PATCH", "/sneaker/"+id, gin.H{"name": "Air Jordan 1 Chicago"}, admin), 200, "rename sneaker")
+	api.expectError(api.do("PATCH", "/sneaker/"+id, gin.H{"imageUrl": "nope"}, admin), 422, "validation_failed", "update sneaker with bad url")
+	api.expectError(api.do("PATCH", "/sneaker/999", gin.H{"name": "Missing"}, admin), 404, "not_found", "update missing sneaker")
+
+	renamed := api.expect(api.do("GET", "/sneaker/"+id, nil, ""), 200, "get renamed sneaker")
+	if data(renamed)["name"] != "Air Jordan 1 Chicago" || data(renamed)["model"] != "Air Jordan 1" {
+		t.Errorf("sneaker = %v, want only the name changed", renamed.body)
+	}
+
+	api.expect(api.do("DELETE", "/sneaker/"+id, nil, admin), 200, "delete sneaker")
+	api.expectError(api.do("GET", "/sneaker/"+id, nil, ""), 404, "not_found", "get deleted sneaker")
+	api.expectError(api.do("DELETE", "/sneaker/"+id, nil, admin), 404, "not_found", "delete deleted sneaker")
+}
+
+func TestProviderHandlers(t *testing.T) {
+	api := newTestAPI(t)
+	admin := api.adminToken()
+
+	created := api.expect(api.do("POST", "/provider/", gin.H{"providerName": "Sneakers Shop"}, admin), 200, "create provider")
+	id := int(data(created)["id"].(float64))
+
+	api.expectError(api.do("POST", "/provider/", gin.H{}, admin), 422, "validation_failed", "create provider without name")
+	api.expectError(api.do("POST", "/provider/", nil, admin), 400, "bad_request", "create provider without body")
+
+	api.expect(api.do("GET", "/provider/"+strconv.Itoa(id), nil, ""), 200, "get provider")
+	api.expect(api.do("PATCH", "/provider/"+strconv.Itoa(id), gin.H{"providerName": "Sneaker Shop"}, admin), 200, "rename provider")
+
+	providers := api.expect(api.do("GET", "/provider/?name=sneaker", nil, ""), 200, "list providers")
+	if items := list(providers); len(items) != 1 || items[0].(map[string]interface{})["providerName"] != "Sneaker Shop" {
+		t.Errorf("providers = %v, want the renamed provider", providers.body)
+	}
+
+	api.store.SetProviderInUse(id, true)
+
+	api.expectError(api.do("DELETE", "/provider/"+strconv.Itoa(id), nil, admin), 409, "conflict", "delete provider in use")
+	api.expectError(api.do("DELETE", "/provider/"+strconv.Itoa(id)+"?cascade=maybe", nil, admin), 400, "bad_request", "delete with bad cascade")
+	api.expect(api.do("DELETE", "/provider/"+strconv.Itoa(id)+"?cascade=true", nil, admin), 200, "cascade delete provider")
+	api.expectError(api.do("GET", "/provider/"+strconv.Itoa(id), nil, ""), 404, "not_found", "get deleted provider")
+}
+
+func TestAlertHandlers(t *testing.T) {
+	api := newTestAPI(t)
+	admin := api.adminToken()
+	id, own := api.userToken("greta@test.com")
+	_, other := api.userToken("someone@test.com")
+
+	created := api.expect(api.do("POST", "/sneaker/", gin.H{"name": "Dunk Low", "model": "Dunk", "brand": "Nike"}, admin), 200, "create sneaker")
+	sneakerId := int(data(created)["id"].(float64))
+
+	created = api.expect(api.do("POST", "/provider/", gin.H{"providerName": "Sneakers Shop"}, admin), 200, "create provider")
+	providerId := int(data(created)["id"].(float64))
+
+	alerts := "/user/" + strconv.Itoa(id) + "/alerts"
+
+	alert := api.expect(api.do("POST", alerts, gin.H{"sneakerId": sneakerId, "providerId": providerId, "targetPrice": 120}, own), 200, "create alert")
+	alertPath := alerts + "/" + strconv.Itoa(int(data(alert)["id"].(float64)))
+
+	api.expectError(api.do("POST", alerts, gin.H{"sneakerId": sneakerId}, other), 403, "forbidden", "create alert for another user")
+	api.expectError(api.do("POST", alerts, gin.H{"providerId": providerId}, own), 422, "validation_failed", "create alert without sneaker")
+	api.expectError(api.do("POST", "/user/999/alerts", gin.H{"sneakerId": sneakerId}, admin), 404, "not_found", "create alert for missing user")
+
+	unknown := api.expectError(api.do("POST", alerts, gin.H{"sneakerId": 999}, own), 422, "validation_failed", "create alert on missing sneaker")
+	if unknown.body["field"] != "sneakerId" {
+		t.Errorf("field = %v, want sneakerId", unknown.body["field"])
+	}
+
+	unknown = api.expectError(api.do("POST", alerts, gin.H{"sneakerId": sneakerId, "providerId": 999}, own), 422, "validation_failed", "create alert on missing provider")
+	if unknown.body["field"] != "providerId" {
+		t.Errorf("field = %v, want providerId", unknown.body["field"])
+	}
+
+	api.expect(api.do("PATCH", alertPath, gin.H{"targetPrice": 99.5}, own), 200, "update alert")
+	api.expectError(api.do("PATCH", alertPath, gin.H{"providerId": 999}, own), 422, "validation_failed", "update alert to a missing provider")
+	api.expectError(api.do("PATCH", alerts+"/999", gin.H{"targetPrice": 80}, own), 404, "not_found", "update missing alert")
+
+	listed := api.expect(api.do("GET", alerts, nil, own), 200, "list alerts")
+	if items := list(listed); len(items) != 1 || items[0].(map[string]interface{})["targetPrice"] != 99.5 {
+		t.Fatalf("alerts = %v, want the updated alert", listed.body)
+	}
+
+	api.expect(api.do("GET", alerts+"/triggered", nil, own), 200, "list triggered alerts")
+
+	// the alert keeps the provider in use
+	api.expectError(api.do("DELETE", "/provider/"+strconv.Itoa(providerId), nil, admin), 409, "conflict", "delete provider with alerts")
+
+	moved := api.expectError(api.do("PATCH", alertPath, gin.H{"sneakerId": sneakerId}, own), 422, "validation_failed", "move alert to another sneaker")
+	if moved.body["field"] != "sneakerId" {
+		t.Errorf("field = %v, want sneakerId", moved.body["field"])
+	}
+
+	api.expectError(api.do("PATCH", alertPath, gin.H{"targetPrice": -1}, own), 422, "validation_failed", "update alert to a negative price")
+	api.expectError(api.do("PATCH", alertPath, gin.H{}, own), 422, "validation_failed", "update alert without fields")
+
+	// zero values are updates too: any restock, at any provider
+	api.expect(api.do("PATCH", alertPath, gin.H{"targetPrice": 0, "providerId": 0}, own), 200, "clear alert price and provider")
+
+	cleared := list(api.expect(api.do("GET", alerts, nil, own), 200, "list cleared alerts"))[0].(map[string]interface{})
+	if cleared["targetPrice"] != float64(0) || cleared["providerId"] != nil {
+		t.Errorf("alert = %v, want target price 0 and no provider", cleared)
+	}
+
+	api.expect(api.do("DELETE", alertPath, nil, own), 200, "delete alert")
+	api.expectError(api.do("DELETE", alertPath, nil, own), 404, "not_found", "delete deleted alert")
+	api.expect(api.do("DELETE", "/provider/"+strconv.Itoa(providerId), nil, admin), 200, "delete provider without alerts")
+}
+
+func TestRoutesWithoutStore(t *testing.T) {
+	api := newTestAPI(t)
+	admin := api.adminToken()
+
+	// the test server has no notification or catalog store and no scraper
+	routes := []struct {
+		method string
+		path   string
+		token  string
+	}{
+		{"GET", "/sneaker/search?q=dunk", ""},
+		{"GET", "/sneaker/availability", ""},
+		{"GET", "/sneaker/offers/best", ""},
+		{"GET", "/sneaker/1/offers", ""},
+		{"GET", "/sneaker/1/scrapper", ""},
+		{"GET", "/sneaker/1/history", ""},
+		{"POST", "/sneaker/1/scrapper/run", admin},
+		{"GET", "/admin/notifications/dead", admin},
+		{"POST", "/admin/notifications/1/retry", admin},
+	}
+
+	for _, route := range routes {
+		api.expectError(api.do(route.method, route.path, nil, route.token), 501, "not_implemented", route.method+" "+route.path)
+	}
+}
+
+func TestUnknownRoute(t *testing.T) {
+	api := newTestAPI(t)
+
+	api.expectError(api.do("GET", "/nope", nil, ""), 404, "not_found", "unknown route")
+}
+
+func TestRequestId(t *testing.T) {
+	api := newTestAPI(t)
+
+	tests := []struct {
+		header string
+		reused bool
+	}{
+		{"abc-1.2_x", true},
+		{"<script>alert(1)</script>", false},
+		{"id with spaces", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", "/nope", nil)
+		request.Header.Set(requestIdHeader, test.header)
+
+		recorder := httptest.NewRecorder()
+		api.handler.ServeHTTP(recorder, request)
+
+		got := recorder.Header().Get(requestIdHeader)
+		if (got == test.header) != test.reused || !validRequestId(got) {
+			t.Errorf("request id %q answered with %q", test.header, got)
+		}
+	}
+}
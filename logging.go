@@ -0,0 +1,83 @@
+package main
+
+import (
+	"github.com/Gretamass/kys-backend/config"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// newLogger builds the logger for the configured level and format. The
+// config has been validated, so both are known values.
+func newLogger(cfg config.LogConfig) *slog.Logger {
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.Level))
+
+	options := &slog.HandlerOptions{Level: level}
+	if cfg.Format == "text" {
+		return slog.New(slog.NewTextHandler(os.Stderr, options))
+	}
+
+	return slog.New(slog.NewJSONHandler(os.Stderr, options))
+}
+
+// fatal logs err and exits. It is only used before the server starts.
+func fatal(err error) {
+	slog.Error(err.Error())
+	os.Exit(1)
+}
+
+// requestLog returns the default logger tagged with the id of the request.
+func requestLog(c *gin.Context) *slog.Logger {
+	return slog.With("request_id", c.GetString(requestIdContextKey))
+}
+
+// requestLogger logs one line per request with its outcome, replacing gin's
+// default logger. It must run after requestId. The db package does not log
+// request errors itself; they reach this line through c.Error and are logged
+// once, tagged with the request id.
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip", c.ClientIP(),
+		}
+
+		if claims, ok := tokenClaims(c); ok {
+			attrs = append(attrs, "user_id", claims.UserId, "role", claims.Role)
+		}
+
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "error", c.Errors.Last().Err.Error())
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		requestLog(c).Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// recovery answers panicking requests with the internal error envelope.
+func recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered any) {
+		requestLog(c).Error("panic", "panic", recovered)
+		respondError(c, http.StatusInternalServerError, "internal error")
+	})
+}
@@ -1,124 +1,254 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/Gretamass/kys-backend/alert"
+	"github.com/Gretamass/kys-backend/auth"
+	"github.com/Gretamass/kys-backend/config"
 	"github.com/Gretamass/kys-backend/db"
+	"github.com/Gretamass/kys-backend/metrics"
+	"github.com/Gretamass/kys-backend/notify"
+	"github.com/Gretamass/kys-backend/provider"
+	"github.com/Gretamass/kys-backend/scraper"
+	"github.com/Gretamass/kys-backend/sneaker"
 	"github.com/Gretamass/kys-backend/user"
-	"github.com/dgrijalva/jwt-go"
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
 type server struct {
-	db *db.DB
+	users     db.UserStore
+	admins    db.AdminStore
+	sneakers  db.SneakerStore
+	providers db.ProviderStore
+	sessions  db.RefreshTokenStore
+	alerts    db.AlertStore
+	// notifications, catalog and scraper may be nil in handler tests, in
+	// which case their routes answer 501
+	notifications db.NotificationStore
+	catalog       db.CatalogStore
+	tokens        *auth.TokenManager
+	scraper       *scraper.Engine
+	scheduler     *scraper.Scheduler
+	dispatcher    *notify.Dispatcher
 }
 
 func main() {
-	dbc, err := db.ConnectDatabase()
+	configPath, configRequired := "config.yaml", false
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		configPath, configRequired = path, true
+	}
+
+	cfg, err := config.Load(configPath, configRequired)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg.Database.Path, os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "create-admin" {
+		if err := runCreateAdmin(cfg.Database.Path, os.Args[2:], os.Stdin); err != nil {
+			fatal(err)
+		}
+		return
 	}
 
+	if err := cfg.Validate(); err != nil {
+		fatal(err)
+	}
+
+	slog.SetDefault(newLogger(cfg.Log))
+
+	dbc, err := db.ConnectDatabase(cfg.Database.Path)
+	if err != nil {
+		fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	srv := &server{
-		db: dbc,
+		users:         dbc,
+		admins:        dbc,
+		sneakers:      dbc,
+		providers:     dbc,
+		sessions:      dbc,
+		alerts:        dbc,
+		notifications: dbc,
+		catalog:       dbc,
+		tokens:        auth.NewTokenManager(cfg.Auth.JWTSecret, "kys-backend", time.Duration(cfg.Auth.TokenTTL), time.Duration(cfg.Auth.RefreshTokenTTL)),
+		scraper:       scraper.NewEngine(dbc),
 	}
 
-	r := gin.Default()
-	r.SetTrustedProxies([]string{"192.168.68.102"})
+	m := metrics.New()
+	m.RegisterDBStats(dbc.Stats)
+	srv.scraper.Observe(m.ObserveScrape)
 
-	userRouter := r.Group("/user")
-	{
-		userRouter.GET("/", srv.getUsers)
-		userRouter.GET("/:id", srv.getUserById)
-		userRouter.POST("/", srv.createUser)
-		userRouter.PATCH("/:id", srv.updateUser)
-		userRouter.DELETE("/:id", srv.deleteUser)
+	srv.dispatcher = notify.NewDispatcher(dbc, notify.DispatcherConfig{
+		PollInterval: 10 * time.Second,
+		MaxAttempts:  5,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   time.Hour,
+	})
+
+	srv.dispatcher.Observe(m.ObserveDelivery)
+	srv.dispatcher.Register(notify.ChannelInbox, notify.NewInboxNotifier(dbc))
+
+	if smtp := cfg.Notify.SMTP; smtp.Addr != "" {
+		srv.dispatcher.Register(notify.ChannelEmail, notify.NewSMTPNotifier(smtp.Addr, smtp.From, smtp.Username, smtp.Password))
+	}
+
+	if url := cfg.Notify.WebhookURL; url != "" {
+		srv.dispatcher.Register(notify.ChannelWebhook, notify.NewWebhookNotifier(&http.Client{Timeout: 10 * time.Second}, url))
+	}
+
+	dbc.SetNotificationChannels(srv.dispatcher.Channels())
+
+	// The workers do not run on ctx: a signal must not interrupt them before
+	// the HTTP server has drained. Stop cancels them after Shutdown.
+	srv.dispatcher.Start(context.Background())
+
+	if path := cfg.Scraper.Adapters; path != "" {
+		configs, err := scraper.LoadHTTPAdapterConfigs(path)
+		if err != nil {
+			fatal(err)
+		}
+
+		client := &http.Client{Timeout: 30 * time.Second}
+		for _, adapterConfig := range configs {
+			adapter, err := scraper.NewHTTPAdapter(client, adapterConfig)
+			if err != nil {
+				fatal(err)
+			}
+			srv.scraper.Register(adapterConfig.ProviderId, adapter)
+		}
+
+		srv.scheduler = scraper.NewScheduler(dbc, srv.scraper.Run, scraper.SchedulerConfig{
+			Interval:            time.Duration(cfg.Scraper.Interval),
+			Concurrency:         cfg.Scraper.Concurrency,
+			ProviderConcurrency: cfg.Scraper.ProviderConcurrency,
+			BaseBackoff:         time.Duration(cfg.Scraper.BaseBackoff),
+			MaxBackoff:          time.Duration(cfg.Scraper.MaxBackoff),
+		})
+		srv.scheduler.Start(context.Background())
 	}
 
-	adminRouter := r.Group("/admin")
-	{
-		adminRouter.GET("/", srv.getAdmins)
-		adminRouter.GET("/:id", srv.getAdminById)
-		adminRouter.POST("/", srv.createAdmin)
-		adminRouter.PATCH("/:id", srv.updateAdmin)
-		adminRouter.DELETE("/:id", srv.deleteAdmin)
+	if err := registerValidations(); err != nil {
+		fatal(err)
 	}
 
-	loginRouter := r.Group("/login")
-	{
-		loginRouter.POST("/", srv.loginUser)
+	r, err := srv.router(cfg.Server, m)
+	if err != nil {
+		fatal(err)
 	}
 
-	sneakerRouter := r.Group("/sneaker")
-	{
-		sneakerRouter.GET("/", srv.getSneakers)
-		sneakerRouter.GET("/info", srv.getSneakersInfo)
-		sneakerRouter.GET("/:id", srv.getSneakerInfo)
-		sneakerRouter.GET("/availability", srv.getSneakersAvailability)
-		sneakerRouter.GET("/:id/scrapper", srv.getSneakerScrapper)
-		//TODO: add missing routers
-		//sneakerRouter.GET("/:id", srv.getSneakerById)
-		//sneakerRouter.POST("/", srv.createSneaker)
-		//sneakerRouter.PATCH("/:id", srv.updateSneaker)
-		//sneakerRouter.DELETE("/:id", srv.deleteSneaker)
+	httpServer := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           r,
+		ReadTimeout:       time.Duration(cfg.Server.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(cfg.Server.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.Server.IdleTimeout),
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
 	}
 
-	providerRouter := r.Group("/provider")
-	{
-		providerRouter.GET("/", srv.getProviders)
-		providerRouter.GET("/:id", srv.getProviderById)
-		//TODO: add missing routers
-		//providerRouter.POST("/", srv.createProvider)
-		//providerRouter.PATCH("/:id", srv.updateProvider)
-		//providerRouter.DELETE("/:id", srv.deleteProvider)
+	serveErr := make(chan error, 1)
+	go func() {
+		if cfg.Server.TLSCertFile != "" {
+			serveErr <- httpServer.ListenAndServeTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		} else {
+			serveErr <- httpServer.ListenAndServe()
+		}
+	}()
+
+	serveFailed := false
+
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		slog.Error("serve", "error", err)
+		serveFailed = true
 	}
+	stop()
 
-	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
-	r.Use(cors.New(config))
+	// drain in-flight requests first, then cancel the background workers and
+	// wait for them before closing the database they write to
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		slog.Error("shutdown", "error", err)
+	}
+
+	if srv.scheduler != nil {
+		srv.scheduler.Stop()
+	}
+
+	srv.dispatcher.Stop()
+
+	if err := dbc.Close(); err != nil {
+		slog.Error("close database", "error", err)
+	}
 
-	r.Run()
+	if serveFailed {
+		os.Exit(1)
+	}
 }
 
 // USER handlers
 func (s *server) getUsers(c *gin.Context) {
-	users, err := s.db.GetUsers()
+	opts, err := parseListOptions(c, "email")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, total, err := s.users.GetUsers(opts)
 
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
 	if users == nil {
-		c.JSON(404, gin.H{"error": "No Users Found"})
+		respondError(c, http.StatusNotFound, "No Users Found")
 		return
 	} else {
-		c.JSON(200, gin.H{"data": users})
+		c.JSON(200, gin.H{"data": users, "meta": listMeta(opts, len(users), total)})
 	}
 }
 
 func (s *server) getUserById(c *gin.Context) {
 	idStr := c.Params.ByName("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		respondError(c, http.StatusBadRequest, "user ID is required")
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect ID"})
+		respondError(c, http.StatusBadRequest, "incorrect ID")
 		return
 	}
 
-	user, err := s.db.GetUserById(id)
+	user, err := s.users.GetUserById(id)
 
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
@@ -128,15 +258,13 @@ func (s *server) getUserById(c *gin.Context) {
 func (s *server) createUser(c *gin.Context) {
 	var newUser user.User
 
-	if err := c.BindJSON(&newUser); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "bad JSON"})
+	if !bindJSON(c, &newUser) {
 		return
 	}
 
-	err := s.db.AddUser(newUser)
+	err := s.users.AddUser(newUser)
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
@@ -148,24 +276,22 @@ func (s *server) updateUser(c *gin.Context) {
 
 	idStr := c.Params.ByName("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		respondError(c, http.StatusBadRequest, "user ID is required")
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect ID"})
+		respondError(c, http.StatusBadRequest, "incorrect ID")
 		return
 	}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "bad JSON"})
+	if !bindPatchJSON(c, &request) {
 		return
 	}
 
-	if err := s.db.UpdateUser(id, request); err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	if err := s.users.UpdateUser(id, request); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -175,19 +301,18 @@ func (s *server) updateUser(c *gin.Context) {
 func (s *server) deleteUser(c *gin.Context) {
 	idStr := c.Params.ByName("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		respondError(c, http.StatusBadRequest, "user ID is required")
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect ID"})
+		respondError(c, http.StatusBadRequest, "incorrect ID")
 		return
 	}
 
-	if err := s.db.DeleteUser(id); err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	if err := s.users.DeleteUser(id); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -195,53 +320,341 @@ func (s *server) deleteUser(c *gin.Context) {
 }
 
 func (s *server) loginUser(c *gin.Context) {
-	var user user.User
+	var request user.Credentials
 
-	if err := c.BindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "bad JSON"})
+	if !bindJSON(c, &request) {
 		return
 	}
 
-	userExists, err := s.db.LoginUser(user)
+	user, userExists, err := s.users.LoginUser(user.User{Email: request.Email, Password: request.Password})
 
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
-	if userExists != true {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "incorrect email or password"})
+	if !userExists {
+		respondError(c, http.StatusUnauthorized, "incorrect email or password")
 		return
 	}
 
-	// generate JWT with user ID as claim
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.Id,
-	})
+	signedToken, refreshToken, err := s.issueTokens(user.Id, auth.RoleUser, "")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"token": signedToken, "refreshToken": refreshToken, "data": user})
+}
+
+// getCurrentUser returns the user or admin the bearer token belongs to.
+func (s *server) getCurrentUser(c *gin.Context) {
+	if currentUser, ok := c.Get(userContextKey); ok {
+		c.JSON(200, gin.H{"data": currentUser, "role": auth.RoleUser})
+		return
+	}
+
+	if currentAdmin, ok := c.Get(adminContextKey); ok {
+		c.JSON(200, gin.H{"data": currentAdmin, "role": auth.RoleAdmin})
+		return
+	}
+
+	respondError(c, http.StatusUnauthorized, "invalid token")
+}
+
+func (s *server) loginAdmin(c *gin.Context) {
+	var request user.Credentials
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	admin, adminExists, err := s.admins.LoginAdmin(user.Admin{Email: request.Email, Password: request.Password})
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !adminExists {
+		respondError(c, http.StatusUnauthorized, "incorrect email or password")
+		return
+	}
+
+	signedToken, refreshToken, err := s.issueTokens(admin.Id, auth.RoleAdmin, "")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"token": signedToken, "refreshToken": refreshToken, "data": admin})
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// refreshTokens exchanges a refresh token for a new access token and a
+// rotated refresh token from the same family.
+func (s *server) refreshTokens(c *gin.Context) {
+	var request refreshRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	previous, err := s.sessions.UseRefreshToken(auth.HashRefreshToken(request.RefreshToken))
+	if err != nil {
+		if errors.Is(err, db.ErrRefreshTokenInvalid) || errors.Is(err, db.ErrRefreshTokenReused) {
+			respondError(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	// the subject may have been deleted without its tokens being revoked
+	switch previous.Role {
+	case auth.RoleUser:
+		_, err = s.users.GetUserById(previous.SubjectId)
+	case auth.RoleAdmin:
+		_, err = s.admins.GetAdminById(previous.SubjectId)
+	default:
+		err = db.ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) || errors.Is(err, db.ErrRefreshTokenInvalid) {
+			respondError(c, http.StatusUnauthorized, db.ErrRefreshTokenInvalid.Error())
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	signedToken, refreshToken, err := s.issueTokens(previous.SubjectId, previous.Role, previous.FamilyId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"token": signedToken, "refreshToken": refreshToken})
+}
+
+// logout revokes the whole family of the presented refresh token.
+func (s *server) logout(c *gin.Context) {
+	var request refreshRequest
+
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	if err := s.sessions.RevokeRefreshTokenFamily(auth.HashRefreshToken(request.RefreshToken)); err != nil {
+		if errors.Is(err, db.ErrRefreshTokenInvalid) {
+			respondError(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Logged out!"})
+}
+
+// issueTokens signs an access token and stores a new refresh token for the
+// subject. An empty familyId starts a new refresh token family.
+func (s *server) issueTokens(subjectId int, role string, familyId string) (string, string, error) {
+	signedToken, err := s.tokens.Issue(subjectId, role)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, stored, err := s.tokens.NewRefreshToken(subjectId, role, familyId)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.sessions.AddRefreshToken(stored); err != nil {
+		return "", "", err
+	}
+
+	return signedToken, refreshToken, nil
+}
+
+// ALERT handlers
+func (s *server) getUserAlerts(c *gin.Context) {
+	idStr := c.Params.ByName("id")
+	if idStr == "" {
+		respondError(c, http.StatusBadRequest, "user ID is required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	alerts, err := s.alerts.GetPriceAlerts(id)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"data": alerts})
+}
+
+func (s *server) getUserTriggeredAlerts(c *gin.Context) {
+	idStr := c.Params.ByName("id")
+	if idStr == "" {
+		respondError(c, http.StatusBadRequest, "user ID is required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	triggered, err := s.alerts.GetTriggeredAlerts(id)
 
-	// sign the token with a secret key
-	signedToken, err := token.SignedString([]byte("mysecretkey"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(200, gin.H{"token": signedToken})
+	c.JSON(200, gin.H{"data": triggered})
+}
+
+func (s *server) createUserAlert(c *gin.Context) {
+	var newAlert alert.PriceAlert
+
+	idStr := c.Params.ByName("id")
+	if idStr == "" {
+		respondError(c, http.StatusBadRequest, "user ID is required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	if !bindJSON(c, &newAlert) {
+		return
+	}
+
+	newAlert.UserId = id
+
+	alertId, err := s.alerts.AddPriceAlert(newAlert)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	newAlert.Id = alertId
+
+	c.JSON(200, gin.H{"success": "Alert added to the database", "data": newAlert})
+}
+
+func (s *server) updateUserAlert(c *gin.Context) {
+	var request alert.PriceAlertUpdate
+
+	idStr := c.Params.ByName("id")
+	alertIdStr := c.Params.ByName("alertId")
+	if idStr == "" || alertIdStr == "" {
+		respondError(c, http.StatusBadRequest, "user ID and alert ID are required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	alertId, err := strconv.Atoi(alertIdStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect alert ID")
+		return
+	}
+
+	if !bindPatchJSON(c, &request) {
+		return
+	}
+
+	if err := s.alerts.UpdatePriceAlert(id, alertId, request); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Alert Updated!"})
+}
+
+func (s *server) deleteUserAlert(c *gin.Context) {
+	idStr := c.Params.ByName("id")
+	alertIdStr := c.Params.ByName("alertId")
+	if idStr == "" || alertIdStr == "" {
+		respondError(c, http.StatusBadRequest, "user ID and alert ID are required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	alertId, err := strconv.Atoi(alertIdStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect alert ID")
+		return
+	}
+
+	if err := s.alerts.DeletePriceAlert(id, alertId); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Alert Deleted!"})
+}
+
+func (s *server) getUserInbox(c *gin.Context) {
+	idStr := c.Params.ByName("id")
+	if idStr == "" {
+		respondError(c, http.StatusBadRequest, "user ID is required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	messages, err := s.notifications.GetInboxMessages(id)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"data": messages})
 }
 
 // ADMIN handlers
 func (s *server) getAdmins(c *gin.Context) {
-	admins, err := s.db.GetAdmins()
+	admins, err := s.admins.GetAdmins()
 
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
 	if admins == nil || len(admins) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No Admins Found"})
+		respondError(c, http.StatusNotFound, "No Admins Found")
 		return
 	} else {
 		c.JSON(http.StatusOK, gin.H{"data": admins})
@@ -251,21 +664,20 @@ func (s *server) getAdmins(c *gin.Context) {
 func (s *server) getAdminById(c *gin.Context) {
 	idStr := c.Params.ByName("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "admin ID is required"})
+		respondError(c, http.StatusBadRequest, "admin ID is required")
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect ID"})
+		respondError(c, http.StatusBadRequest, "incorrect ID")
 		return
 	}
 
-	admin, err := s.db.GetAdminById(id)
+	admin, err := s.admins.GetAdminById(id)
 
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
@@ -275,15 +687,13 @@ func (s *server) getAdminById(c *gin.Context) {
 func (s *server) createAdmin(c *gin.Context) {
 	var newAdmin user.Admin
 
-	if err := c.BindJSON(&newAdmin); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "bad JSON"})
+	if !bindJSON(c, &newAdmin) {
 		return
 	}
 
-	err := s.db.AddAdmin(newAdmin)
+	err := s.admins.AddAdmin(newAdmin)
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
@@ -295,24 +705,22 @@ func (s *server) updateAdmin(c *gin.Context) {
 
 	idStr := c.Params.ByName("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "admin ID is required"})
+		respondError(c, http.StatusBadRequest, "admin ID is required")
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect ID"})
+		respondError(c, http.StatusBadRequest, "incorrect ID")
 		return
 	}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "bad JSON"})
+	if !bindPatchJSON(c, &request) {
 		return
 	}
 
-	if err := s.db.UpdateAdmin(id, request); err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	if err := s.admins.UpdateAdmin(id, request); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -322,78 +730,143 @@ func (s *server) updateAdmin(c *gin.Context) {
 func (s *server) deleteAdmin(c *gin.Context) {
 	idStr := c.Params.ByName("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "admin ID is required"})
+		respondError(c, http.StatusBadRequest, "admin ID is required")
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect ID"})
+		respondError(c, http.StatusBadRequest, "incorrect ID")
 		return
 	}
 
-	if err := s.db.DeleteAdmin(id); err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	if err := s.admins.DeleteAdmin(id); err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.JSON(200, gin.H{"message": "Admin Deleted!"})
 }
 
+func (s *server) getDeadNotifications(c *gin.Context) {
+	notifications, err := s.notifications.GetDeadNotifications()
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"data": notifications})
+}
+
+func (s *server) retryNotification(c *gin.Context) {
+	idStr := c.Params.ByName("id")
+	if idStr == "" {
+		respondError(c, http.StatusBadRequest, "notification ID is required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	if err := s.notifications.RetryNotification(id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Notification Requeued!"})
+}
+
 // SNEAKER handlers
 func (s *server) getSneakers(c *gin.Context) {
-	sneakers, err := s.db.GetSneakers()
+	opts, err := parseListOptions(c, "brand", "model", "name")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sneakers, total, err := s.sneakers.GetSneakers(opts)
 
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
 	if sneakers == nil {
-		c.JSON(404, gin.H{"error": "No Sneakers Found"})
+		respondError(c, http.StatusNotFound, "No Sneakers Found")
 		return
 	} else {
-		c.JSON(200, gin.H{"data": sneakers})
+		c.JSON(200, gin.H{"data": sneakers, "meta": listMeta(opts, len(sneakers), total)})
 	}
 }
 
 func (s *server) getSneakersInfo(c *gin.Context) {
-	sneakers, err := s.db.GetSneakersInfo()
+	opts, err := parseListOptions(c, "brand", "model", "name")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sneakers, total, err := s.sneakers.GetSneakersInfo(opts)
 
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
 	if sneakers == nil {
-		c.JSON(404, gin.H{"error": "No Sneakers Found"})
+		respondError(c, http.StatusNotFound, "No Sneakers Found")
 		return
 	} else {
-		c.JSON(200, gin.H{"data": sneakers})
+		c.JSON(200, gin.H{"data": sneakers, "meta": listMeta(opts, len(sneakers), total)})
+	}
+}
+
+// searchSneakers matches every word of q against the sneaker name, model,
+// brand and information, the last word as a prefix.
+func (s *server) searchSneakers(c *gin.Context) {
+	q := c.Query("q")
+	if strings.TrimSpace(q) == "" {
+		respondError(c, http.StatusBadRequest, "search query is required")
+		return
+	}
+
+	opts, err := parseListOptions(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
 	}
+
+	results, total, err := s.catalog.SearchSneakers(q, opts)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"data": results, "meta": listMeta(opts, len(results), total)})
 }
 
 func (s *server) getSneakerInfo(c *gin.Context) {
 	idStr := c.Params.ByName("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "sneaker ID is required"})
+		respondError(c, http.StatusBadRequest, "sneaker ID is required")
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect ID"})
+		respondError(c, http.StatusBadRequest, "incorrect ID")
 		return
 	}
 
-	sneakerInfo, err := s.db.GetSneakerInfo(id)
+	sneakerInfo, err := s.sneakers.GetSneakerInfo(id)
 
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
@@ -401,84 +874,451 @@ func (s *server) getSneakerInfo(c *gin.Context) {
 }
 
 func (s *server) getSneakersAvailability(c *gin.Context) {
-	sneakers, err := s.db.GetSneakersAvailability()
+	var sizeUS float64
+
+	if label := c.Query("size"); label != "" {
+		size, err := sneaker.ParseSize(label, c.DefaultQuery("sizeSystem", sneaker.SizeUS))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		sizeUS = size
+	}
+
+	sneakers, err := s.catalog.GetSneakersAvailability(sizeUS)
 
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
 	if sneakers == nil {
-		c.JSON(404, gin.H{"error": "No Sneakers Found"})
+		respondError(c, http.StatusNotFound, "No Sneakers Found")
 		return
 	} else {
 		c.JSON(200, gin.H{"data": sneakers})
 	}
 }
 
+func (s *server) getSneakerOffers(c *gin.Context) {
+	idStr := c.Params.ByName("id")
+	if idStr == "" {
+		respondError(c, http.StatusBadRequest, "sneaker ID is required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	offers, err := s.catalog.GetSneakerOffers(id)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"data": offers})
+}
+
+func (s *server) getBestOffers(c *gin.Context) {
+	offers, err := s.catalog.GetBestOffers()
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"data": offers})
+}
+
 func (s *server) getSneakerScrapper(c *gin.Context) {
 	idStr := c.Params.ByName("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "sneaker ID is required"})
+		respondError(c, http.StatusBadRequest, "sneaker ID is required")
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect ID"})
+		respondError(c, http.StatusBadRequest, "incorrect ID")
 		return
 	}
 
-	sneakerInfo, err := s.db.GetSneakerScrapper(id)
+	sneakerInfo, err := s.catalog.GetSneakerScrapper(id)
 
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
+	if s.scheduler != nil {
+		for i := range sneakerInfo {
+			for j, scrapper := range sneakerInfo[i].Scrapper {
+				if status, ok := s.scheduler.Status(scrapper.Id); ok {
+					sneakerInfo[i].Scrapper[j].Status = &status
+				}
+			}
+		}
+	}
+
 	c.JSON(200, gin.H{"data": sneakerInfo})
 }
 
+func (s *server) createSneaker(c *gin.Context) {
+	var newSneaker sneaker.Sneaker
+
+	if !bindJSON(c, &newSneaker) {
+		return
+	}
+
+	id, err := s.sneakers.AddSneaker(newSneaker)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	newSneaker.Id = id
+
+	c.JSON(200, gin.H{"success": "Sneaker added to the database", "data": newSneaker})
+}
+
+func (s *server) updateSneaker(c *gin.Context) {
+	var request sneaker.Sneaker
+
+	idStr := c.Params.ByName("id")
+	if idStr == "" {
+		respondError(c, http.StatusBadRequest, "sneaker ID is required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	if !bindPatchJSON(c, &request) {
+		return
+	}
+
+	if err := s.sneakers.UpdateSneaker(id, request); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Sneaker Updated!"})
+}
+
+func (s *server) deleteSneaker(c *gin.Context) {
+	idStr := c.Params.ByName("id")
+	if idStr == "" {
+		respondError(c, http.StatusBadRequest, "sneaker ID is required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	if err := s.sneakers.DeleteSneaker(id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Sneaker Deleted!"})
+}
+
+// runSneakerScrapper scrapes every provider configured for the sneaker and
+// reports the outcome per scrapper row.
+func (s *server) runSneakerScrapper(c *gin.Context) {
+	idStr := c.Params.ByName("id")
+	if idStr == "" {
+		respondError(c, http.StatusBadRequest, "sneaker ID is required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	sneakerScrappers, err := s.catalog.GetSneakerScrapper(id)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	results := make([]gin.H, 0)
+
+	for _, sneakerScrapper := range sneakerScrappers {
+		for _, scrapper := range sneakerScrapper.Scrapper {
+			availability, err := s.scraper.Run(c.Request.Context(), scrapper)
+			if err != nil {
+				requestLog(c).Warn("scrape failed", "scrapper_id", scrapper.Id, "error", err)
+				results = append(results, gin.H{"scrapperId": scrapper.Id, "error": err.Error()})
+				continue
+			}
+
+			results = append(results, gin.H{"scrapperId": scrapper.Id, "availability": availability})
+		}
+	}
+
+	if len(results) == 0 {
+		respondError(c, http.StatusNotFound, "No Scrappers Found")
+		return
+	}
+
+	c.JSON(200, gin.H{"data": results})
+}
+
+// getSneakerHistory returns the price history of a sneaker per provider.
+// from and to accept a date (to is inclusive) or an RFC 3339 timestamp and
+// interval=daily downsamples the series to daily min/max/close.
+func (s *server) getSneakerHistory(c *gin.Context) {
+	idStr := c.Params.ByName("id")
+	if idStr == "" {
+		respondError(c, http.StatusBadRequest, "sneaker ID is required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	from, _, err := parseTimeParam(c.Query("from"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect from date")
+		return
+	}
+
+	to, dateOnly, err := parseTimeParam(c.Query("to"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect to date")
+		return
+	}
+
+	if dateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	interval := c.DefaultQuery("interval", "raw")
+	if interval != "raw" && interval != "daily" {
+		respondError(c, http.StatusBadRequest, "interval must be raw or daily")
+		return
+	}
+
+	history, err := s.catalog.GetPriceHistory(id, from, to)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if interval == "daily" {
+		for i := range history {
+			history[i].Downsample()
+		}
+	}
+
+	c.JSON(200, gin.H{"data": history})
+}
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// parseListOptions reads the limit, cursor and sort query parameters and the
+// given filter parameters. sort takes a field name, prefixed with - for
+// descending order. The cursor is the nextCursor of the previous page.
+func parseListOptions(c *gin.Context, filters ...string) (db.ListOptions, error) {
+	opts := db.ListOptions{
+		Limit:   defaultListLimit,
+		Filters: make(map[string]string),
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxListLimit {
+			return db.ListOptions{}, fmt.Errorf("limit must be between 1 and %d", maxListLimit)
+		}
+		opts.Limit = value
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		value, err := strconv.Atoi(cursor)
+		if err != nil || value < 0 {
+			return db.ListOptions{}, errors.New("incorrect cursor")
+		}
+		opts.Offset = value
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		opts.Desc = strings.HasPrefix(sort, "-")
+		opts.Sort = strings.TrimPrefix(sort, "-")
+	}
+
+	for _, filter := range filters {
+		if value := c.Query(filter); value != "" {
+			opts.Filters[filter] = value
+		}
+	}
+
+	return opts, nil
+}
+
+// listMeta describes a page returned for opts. nextCursor is only set when
+// more rows follow.
+func listMeta(opts db.ListOptions, count int, total int) gin.H {
+	meta := gin.H{"total": total, "limit": opts.Limit}
+
+	if next := opts.Offset + count; count > 0 && next < total {
+		meta["nextCursor"] = strconv.Itoa(next)
+	}
+
+	return meta
+}
+
+// parseTimeParam parses a query parameter holding either a date or an RFC 3339
+// timestamp. An empty value gives the zero time.
+func parseTimeParam(value string) (time.Time, bool, error) {
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, true, nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, value)
+	return timestamp, false, err
+}
+
 // PROVIDER handlers
 func (s *server) getProviders(c *gin.Context) {
-	sneakers, err := s.db.GetProviders()
+	opts, err := parseListOptions(c, "name")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sneakers, total, err := s.providers.GetProviders(opts)
 
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
 	if sneakers == nil {
-		c.JSON(404, gin.H{"error": "No Providers Found"})
+		respondError(c, http.StatusNotFound, "No Providers Found")
 		return
 	} else {
-		c.JSON(200, gin.H{"data": sneakers})
+		c.JSON(200, gin.H{"data": sneakers, "meta": listMeta(opts, len(sneakers), total)})
 	}
 }
 
 func (s *server) getProviderById(c *gin.Context) {
 	idStr := c.Params.ByName("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "provider ID is required"})
+		respondError(c, http.StatusBadRequest, "provider ID is required")
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect ID"})
+		respondError(c, http.StatusBadRequest, "incorrect ID")
 		return
 	}
 
-	providerInfo, err := s.db.GetProviderById(id)
+	providerInfo, err := s.providers.GetProviderById(id)
 
 	if err != nil {
-		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(200, gin.H{"data": providerInfo})
 }
+
+func (s *server) createProvider(c *gin.Context) {
+	var newProvider provider.ProviderInformation
+
+	if !bindJSON(c, &newProvider) {
+		return
+	}
+
+	id, err := s.providers.AddProvider(newProvider)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	newProvider.Id = float64(id)
+
+	c.JSON(200, gin.H{"success": "Provider added to the database", "data": newProvider})
+}
+
+func (s *server) updateProvider(c *gin.Context) {
+	var request provider.ProviderInformation
+
+	idStr := c.Params.ByName("id")
+	if idStr == "" {
+		respondError(c, http.StatusBadRequest, "provider ID is required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	if !bindPatchJSON(c, &request) {
+		return
+	}
+
+	if err := s.providers.UpdateProvider(id, request); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Provider Updated!"})
+}
+
+// deleteProvider refuses to remove a provider that still has availability,
+// scrapper, price history or alert rows unless called with ?cascade=true.
+func (s *server) deleteProvider(c *gin.Context) {
+	idStr := c.Params.ByName("id")
+	if idStr == "" {
+		respondError(c, http.StatusBadRequest, "provider ID is required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect ID")
+		return
+	}
+
+	cascade, err := strconv.ParseBool(c.DefaultQuery("cascade", "false"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "incorrect cascade value")
+		return
+	}
+
+	if err := s.providers.DeleteProvider(id, cascade); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Provider Deleted!"})
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dbStatsCollector reads the database/sql pool statistics on every scrape.
+type dbStatsCollector struct {
+	stats func() sql.DBStats
+}
+
+var (
+	dbMaxOpen = prometheus.NewDesc(
+		namespace+"_db_max_open_connections",
+		"Maximum number of open connections to the database.", nil, nil)
+	dbOpen = prometheus.NewDesc(
+		namespace+"_db_open_connections",
+		"Established connections, in use and idle.", nil, nil)
+	dbInUse = prometheus.NewDesc(
+		namespace+"_db_in_use_connections",
+		"Connections currently in use.", nil, nil)
+	dbIdle = prometheus.NewDesc(
+		namespace+"_db_idle_connections",
+		"Idle connections.", nil, nil)
+	dbWaitCount = prometheus.NewDesc(
+		namespace+"_db_wait_count_total",
+		"Connections waited for.", nil, nil)
+	dbWaitDuration = prometheus.NewDesc(
+		namespace+"_db_wait_duration_seconds_total",
+		"Time spent waiting for a connection.", nil, nil)
+	dbMaxIdleClosed = prometheus.NewDesc(
+		namespace+"_db_max_idle_closed_total",
+		"Connections closed because of the idle connection limit.", nil, nil)
+	dbMaxIdleTimeClosed = prometheus.NewDesc(
+		namespace+"_db_max_idle_time_closed_total",
+		"Connections closed because of the idle time limit.", nil, nil)
+	dbMaxLifetimeClosed = prometheus.NewDesc(
+		namespace+"_db_max_lifetime_closed_total",
+		"Connections closed because of the connection lifetime limit.", nil, nil)
+)
+
+func (c *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbMaxOpen
+	ch <- dbOpen
+	ch <- dbInUse
+	ch <- dbIdle
+	ch <- dbWaitCount
+	ch <- dbWaitDuration
+	ch <- dbMaxIdleClosed
+	ch <- dbMaxIdleTimeClosed
+	ch <- dbMaxLifetimeClosed
+}
+
+func (c *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+
+	ch <- prometheus.MustNewConstMetric(dbMaxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(dbOpen, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(dbInUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(dbIdle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(dbWaitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(dbWaitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(dbMaxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(dbMaxIdleTimeClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed))
+	ch <- prometheus.MustNewConstMetric(dbMaxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/Gretamass/kys-backend/notify"
+	"github.com/Gretamass/kys-backend/scraper"
+	"github.com/Gretamass/kys-backend/sneaker"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "kys"
+
+// Metrics holds the collectors exposed on the metrics endpoint. It uses its
+// own registry so only what is registered here gets exposed.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	scrapes         *prometheus.CounterVec
+	scrapeDuration  *prometheus.HistogramVec
+	deliveries      *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		scrapes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "scraper",
+			Name:      "runs_total",
+			Help:      "Scrapper runs by provider and outcome.",
+		}, []string{"provider", "outcome"}),
+		scrapeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "scraper",
+			Name:      "run_duration_seconds",
+			Help:      "Scrapper run latency by provider.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"provider"}),
+		deliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "notify",
+			Name:      "deliveries_total",
+			Help:      "Alert notification delivery attempts by channel and outcome.",
+		}, []string{"channel", "outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.scrapes,
+		m.scrapeDuration,
+		m.deliveries,
+	)
+
+	return m
+}
+
+// Handler serves the registered metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a finished HTTP request. route is the route pattern,
+// not the raw path, to keep the number of series bounded.
+func (m *Metrics) ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.requestDuration.WithLabelValues(method, route).Observe(elapsed.Seconds())
+}
+
+// ObserveScrape is a scraper.RunObserver.
+func (m *Metrics) ObserveScrape(scrapper sneaker.Scrapper, err error, elapsed time.Duration) {
+	provider := strconv.Itoa(scrapper.ProviderId)
+
+	outcome := "success"
+	switch {
+	case errors.Is(err, scraper.ErrNoAdapter):
+		outcome = "no_adapter"
+	case err != nil:
+		outcome = "error"
+	}
+
+	m.scrapes.WithLabelValues(provider, outcome).Inc()
+	m.scrapeDuration.WithLabelValues(provider).Observe(elapsed.Seconds())
+}
+
+// ObserveDelivery is a notify.DeliveryObserver. Failed attempts count as
+// "retry" while they will be retried and as "dead" once they will not.
+func (m *Metrics) ObserveDelivery(notification notify.Notification, err error, dead bool) {
+	outcome := "delivered"
+	switch {
+	case dead:
+		outcome = "dead"
+	case err != nil:
+		outcome = "retry"
+	}
+
+	m.deliveries.WithLabelValues(notification.Channel, outcome).Inc()
+}
+
+// RegisterDBStats exposes the connection pool statistics reported by stats.
+func (m *Metrics) RegisterDBStats(stats func() sql.DBStats) {
+	m.registry.MustRegister(&dbStatsCollector{stats: stats})
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"github.com/Gretamass/kys-backend/auth"
+	"github.com/Gretamass/kys-backend/db"
+	"github.com/Gretamass/kys-backend/metrics"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	claimsContextKey = "claims"
+	userContextKey   = "user"
+	adminContextKey  = "admin"
+
+	requestIdContextKey = "requestId"
+)
+
+// authRequired validates the bearer token and stores its claims together
+// with the authenticated user or admin in the context.
+func (s *server) authRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		tokenString := strings.TrimPrefix(header, "Bearer ")
+		if header == "" || tokenString == header {
+			respondError(c, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		claims, err := s.tokens.Parse(tokenString)
+		if err != nil {
+			respondError(c, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		switch claims.Role {
+		case auth.RoleUser:
+			currentUser, err := s.users.GetUserById(claims.UserId)
+			if err != nil {
+				tokenSubjectError(c, err)
+				return
+			}
+			c.Set(userContextKey, currentUser)
+		case auth.RoleAdmin:
+			currentAdmin, err := s.admins.GetAdminById(claims.UserId)
+			if err != nil {
+				tokenSubjectError(c, err)
+				return
+			}
+			c.Set(adminContextKey, currentAdmin)
+		default:
+			respondError(c, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		c.Set(claimsContextKey, claims)
+		c.Next()
+	}
+}
+
+// tokenSubjectError rejects a token whose user or admin no longer exists and
+// reports other lookup failures as internal errors.
+func tokenSubjectError(c *gin.Context, err error) {
+	if errors.Is(err, db.ErrNotFound) {
+		respondError(c, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	c.Error(err)
+	c.Abort()
+}
+
+// requireRole only lets through requests whose token carries role. It must
+// run after authRequired.
+func requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := tokenClaims(c)
+		if !ok || claims.Role != role {
+			respondError(c, http.StatusForbidden, "forbidden")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requireSelfOrAdmin lets admins through and restricts users to routes whose
+// :id parameter is their own id. It must run after authRequired.
+func requireSelfOrAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := tokenClaims(c)
+		if !ok {
+			respondError(c, http.StatusForbidden, "forbidden")
+			return
+		}
+
+		if claims.Role != auth.RoleAdmin && c.Params.ByName("id") != strconv.Itoa(claims.UserId) {
+			respondError(c, http.StatusForbidden, "forbidden")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func tokenClaims(c *gin.Context) (*auth.Claims, bool) {
+	value, ok := c.Get(claimsContextKey)
+	if !ok {
+		return nil, false
+	}
+
+	claims, ok := value.(*auth.Claims)
+	return claims, ok
+}
+
+// requestMetrics records the count and latency of every request by route
+// pattern. Requests that match no route share the "unmatched" route.
+func requestMetrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		m.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
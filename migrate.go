@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Gretamass/kys-backend/db"
+	"strconv"
+)
+
+const migrateUsage = "usage: kys-backend migrate up | down [steps] | status"
+
+// runMigrate handles the migrate subcommand, which applies or reverts
+// migrations without starting the server.
+func runMigrate(path string, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	dbc, err := db.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer dbc.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := dbc.MigrateUp()
+		fmt.Printf("applied %v\n", applied)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("invalid steps %q", args[1])
+			}
+		}
+		reverted, err := dbc.MigrateDown(steps)
+		fmt.Printf("reverted %v\n", reverted)
+		return err
+	case "status":
+		status, err := dbc.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, m := range status {
+			applied := "pending"
+			if m.AppliedAt != nil {
+				applied = m.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d %-20s %s\n", m.Version, m.Name, applied)
+		}
+		return nil
+	}
+
+	return errors.New(migrateUsage)
+}
@@ -0,0 +1,186 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Queue is the SQLite backed delivery queue.
+type Queue interface {
+	GetDueNotifications(now time.Time, limit int) ([]Notification, error)
+	MarkNotificationDelivered(id int) error
+	// MarkNotificationFailed records a failed attempt. The notification is
+	// retried at nextAttemptAt unless dead is set.
+	MarkNotificationFailed(id int, lastError string, nextAttemptAt time.Time, dead bool) error
+}
+
+type DispatcherConfig struct {
+	// PollInterval between two looks at the queue.
+	PollInterval time.Duration
+	// BatchSize is the maximum number of notifications sent per poll.
+	BatchSize int
+	// MaxAttempts before a notification is moved to the dead letters.
+	MaxAttempts int
+	// BaseBackoff is the delay after the first failed attempt. It doubles on
+	// every further failure up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DeliveryObserver is told about every delivery attempt. err is nil when the
+// notification was delivered and dead is set when it will not be retried.
+type DeliveryObserver func(notification Notification, err error, dead bool)
+
+// Dispatcher delivers queued notifications through the notifier registered
+// for their channel and retries failures with exponential backoff.
+type Dispatcher struct {
+	queue     Queue
+	notifiers map[string]Notifier
+	config    DispatcherConfig
+	observers []DeliveryObserver
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewDispatcher(queue Queue, config DispatcherConfig) *Dispatcher {
+	if config.BatchSize < 1 {
+		config.BatchSize = 50
+	}
+
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+
+	return &Dispatcher{
+		queue:     queue,
+		notifiers: make(map[string]Notifier),
+		config:    config,
+	}
+}
+
+// Register sets the notifier for channel. It must be called before Start.
+func (d *Dispatcher) Register(channel string, notifier Notifier) {
+	d.notifiers[channel] = notifier
+}
+
+// Observe adds an observer called after every delivery attempt. It must be
+// called before Start.
+func (d *Dispatcher) Observe(observer DeliveryObserver) {
+	d.observers = append(d.observers, observer)
+}
+
+// Channels lists the registered channels.
+func (d *Dispatcher) Channels() []string {
+	channels := make([]string, 0, len(d.notifiers))
+	for channel := range d.notifiers {
+		channels = append(channels, channel)
+	}
+
+	return channels
+}
+
+// Start polls the queue every PollInterval until ctx is cancelled or Stop
+// is called.
+func (d *Dispatcher) Start(ctx context.Context) {
+	ctx, d.cancel = context.WithCancel(ctx)
+	d.done = make(chan struct{})
+
+	go func() {
+		defer close(d.done)
+
+		ticker := time.NewTicker(d.config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			d.deliverDue(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the current batch to finish.
+func (d *Dispatcher) Stop() {
+	if d.cancel == nil {
+		return
+	}
+
+	d.cancel()
+	<-d.done
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	notifications, err := d.queue.GetDueNotifications(time.Now(), d.config.BatchSize)
+	if err != nil {
+		slog.Error("load due notifications", "error", err)
+		return
+	}
+
+	for _, notification := range notifications {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := d.deliver(ctx, notification); err != nil {
+			slog.Warn("notification delivery failed", "notification_id", notification.Id, "error", err)
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, notification Notification) error {
+	notifier, ok := d.notifiers[notification.Channel]
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("no notifier registered for channel %q", notification.Channel)
+	} else {
+		err = notifier.Notify(ctx, notification)
+	}
+
+	if err == nil {
+		d.observe(notification, nil, false)
+		return d.queue.MarkNotificationDelivered(notification.Id)
+	}
+
+	// Cancelled by Stop rather than failed: the notification stays due and is
+	// sent on the next start without using up an attempt.
+	if ctx.Err() != nil {
+		return fmt.Errorf("notification %d via %s interrupted: %w", notification.Id, notification.Channel, err)
+	}
+
+	attempts := notification.Attempts + 1
+	dead := attempts >= d.config.MaxAttempts
+	d.observe(notification, err, dead)
+	nextAttemptAt := time.Now().Add(d.backoff(attempts))
+
+	if markErr := d.queue.MarkNotificationFailed(notification.Id, err.Error(), nextAttemptAt, dead); markErr != nil {
+		return markErr
+	}
+
+	return fmt.Errorf("notification %d via %s: %w", notification.Id, notification.Channel, err)
+}
+
+func (d *Dispatcher) observe(notification Notification, err error, dead bool) {
+	for _, observer := range d.observers {
+		observer(notification, err, dead)
+	}
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.BaseBackoff
+	for i := 1; i < attempts && i < 32; i++ {
+		delay *= 2
+		if d.config.MaxBackoff > 0 && delay >= d.config.MaxBackoff {
+			return d.config.MaxBackoff
+		}
+	}
+
+	return delay
+}
@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type failedAttempt struct {
+	id            int
+	lastError     string
+	nextAttemptAt time.Time
+	dead          bool
+}
+
+type fakeQueue struct {
+	due       []Notification
+	delivered []int
+	failed    []failedAttempt
+}
+
+func (q *fakeQueue) GetDueNotifications(now time.Time, limit int) ([]Notification, error) {
+	if len(q.due) > limit {
+		return q.due[:limit], nil
+	}
+	return q.due, nil
+}
+
+func (q *fakeQueue) MarkNotificationDelivered(id int) error {
+	q.delivered = append(q.delivered, id)
+	return nil
+}
+
+func (q *fakeQueue) MarkNotificationFailed(id int, lastError string, nextAttemptAt time.Time, dead bool) error {
+	q.failed = append(q.failed, failedAttempt{id, lastError, nextAttemptAt, dead})
+	return nil
+}
+
+type notifierFunc func(ctx context.Context, notification Notification) error
+
+func (f notifierFunc) Notify(ctx context.Context, notification Notification) error {
+	return f(ctx, notification)
+}
+
+func TestDispatcherDeliversAndRetries(t *testing.T) {
+	queue := &fakeQueue{due: []Notification{
+		{Id: 1, Channel: ChannelInbox},
+		{Id: 2, Channel: ChannelWebhook, Attempts: 0},
+		{Id: 3, Channel: ChannelWebhook, Attempts: 2},
+		{Id: 4, Channel: ChannelEmail},
+	}}
+
+	dispatcher := NewDispatcher(queue, DispatcherConfig{MaxAttempts: 3, BaseBackoff: time.Minute, MaxBackoff: time.Hour})
+	dispatcher.Register(ChannelInbox, notifierFunc(func(ctx context.Context, notification Notification) error {
+		return nil
+	}))
+	dispatcher.Register(ChannelWebhook, notifierFunc(func(ctx context.Context, notification Notification) error {
+		return errors.New("webhook down")
+	}))
+
+	outcomes := make(map[int]string)
+	dispatcher.Observe(func(notification Notification, err error, dead bool) {
+		switch {
+		case dead:
+			outcomes[notification.Id] = "dead"
+		case err != nil:
+			outcomes[notification.Id] = "retry"
+		default:
+			outcomes[notification.Id] = "delivered"
+		}
+	})
+
+	start := time.Now()
+	dispatcher.deliverDue(context.Background())
+
+	if len(queue.delivered) != 1 || queue.delivered[0] != 1 {
+		t.Errorf("delivered = %v, want [1]", queue.delivered)
+	}
+
+	if len(queue.failed) != 3 {
+		t.Fatalf("failed = %+v, want 3 attempts", queue.failed)
+	}
+
+	retry := queue.failed[0]
+	if retry.id != 2 || retry.dead || retry.lastError != "webhook down" {
+		t.Errorf("first failure = %+v, want notification 2 to be retried", retry)
+	}
+
+	if wait := retry.nextAttemptAt.Sub(start); wait < time.Minute || wait > time.Minute+time.Second {
+		t.Errorf("retry scheduled after %v, want the base backoff of 1m", wait)
+	}
+
+	if dead := queue.failed[1]; dead.id != 3 || !dead.dead {
+		t.Errorf("second failure = %+v, want notification 3 dead after its last attempt", dead)
+	}
+
+	// no notifier is registered for email
+	if unknown := queue.failed[2]; unknown.id != 4 || unknown.dead {
+		t.Errorf("third failure = %+v, want notification 4 to be retried", unknown)
+	}
+
+	want := map[int]string{1: "delivered", 2: "retry", 3: "dead", 4: "retry"}
+	for id, outcome := range want {
+		if outcomes[id] != outcome {
+			t.Errorf("observed outcome of %d = %q, want %q", id, outcomes[id], outcome)
+		}
+	}
+}
+
+func TestDispatcherBatchSize(t *testing.T) {
+	queue := &fakeQueue{due: []Notification{{Id: 1}, {Id: 2}, {Id: 3}}}
+
+	dispatcher := NewDispatcher(queue, DispatcherConfig{BatchSize: 2})
+	dispatcher.Register("", notifierFunc(func(ctx context.Context, notification Notification) error {
+		return nil
+	}))
+
+	dispatcher.deliverDue(context.Background())
+
+	if len(queue.delivered) != 2 {
+		t.Errorf("delivered = %v, want a batch of 2", queue.delivered)
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	dispatcher := NewDispatcher(&fakeQueue{}, DispatcherConfig{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second})
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range want {
+		if got := dispatcher.backoff(i + 1); got != delay {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, delay)
+		}
+	}
+}
+
+func TestDispatcherStopsBetweenNotifications(t *testing.T) {
+	queue := &fakeQueue{due: []Notification{{Id: 1}, {Id: 2}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	dispatcher := NewDispatcher(queue, DispatcherConfig{})
+	dispatcher.Register("", notifierFunc(func(ctx context.Context, notification Notification) error {
+		cancel()
+		return nil
+	}))
+
+	dispatcher.deliverDue(ctx)
+
+	if len(queue.delivered) != 1 {
+		t.Errorf("delivered = %v, want only the notification sent before cancellation", queue.delivered)
+	}
+}
+
+func TestDispatcherDoesNotCountInterruptedDeliveries(t *testing.T) {
+	queue := &fakeQueue{due: []Notification{{Id: 1, Channel: ChannelWebhook, Attempts: 2}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	dispatcher := NewDispatcher(queue, DispatcherConfig{MaxAttempts: 3})
+	dispatcher.Register(ChannelWebhook, notifierFunc(func(ctx context.Context, notification Notification) error {
+		cancel()
+		return ctx.Err()
+	}))
+
+	observed := false
+	dispatcher.Observe(func(notification Notification, err error, dead bool) {
+		observed = true
+	})
+
+	dispatcher.deliverDue(ctx)
+
+	if len(queue.failed) != 0 || len(queue.delivered) != 0 || observed {
+		t.Errorf("failed = %+v, delivered = %v, observed = %v, want the interrupted delivery left due", queue.failed, queue.delivered, observed)
+	}
+}
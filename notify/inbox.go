@@ -0,0 +1,27 @@
+package notify
+
+import "context"
+
+// InboxStore persists in-app inbox messages.
+type InboxStore interface {
+	AddInboxMessage(message InboxMessage) error
+}
+
+// InboxNotifier delivers notifications to the user's in-app inbox.
+type InboxNotifier struct {
+	store InboxStore
+}
+
+func NewInboxNotifier(store InboxStore) *InboxNotifier {
+	return &InboxNotifier{
+		store: store,
+	}
+}
+
+func (n *InboxNotifier) Notify(ctx context.Context, notification Notification) error {
+	return n.store.AddInboxMessage(InboxMessage{
+		UserId:  notification.UserId,
+		Subject: notification.Subject,
+		Body:    notification.Body,
+	})
+}
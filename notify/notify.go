@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"github.com/Gretamass/kys-backend/alert"
+)
+
+// Delivery channels.
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelInbox   = "inbox"
+)
+
+// Queue statuses.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// Notification is a message waiting in or delivered from the queue.
+type Notification struct {
+	Id            int    `json:"id"`
+	UserId        int    `json:"userId"`
+	Channel       string `json:"channel"`
+	Recipient     string `json:"recipient,omitempty"`
+	Subject       string `json:"subject"`
+	Body          string `json:"body"`
+	Status        string `json:"status"`
+	Attempts      int    `json:"attempts"`
+	LastError     string `json:"lastError,omitempty"`
+	NextAttemptAt string `json:"nextAttemptAt,omitempty"`
+	CreatedAt     string `json:"createdAt,omitempty"`
+}
+
+// InboxMessage is a notification delivered to the in-app inbox.
+type InboxMessage struct {
+	Id        int    `json:"id"`
+	UserId    int    `json:"userId"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+// Notifier delivers a notification over one channel.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// AlertNotification builds the message sent when a price alert fires.
+func AlertNotification(triggered alert.TriggeredAlert, sneakerName string, providerName string) (string, string) {
+	subject := fmt.Sprintf("Price alert: %s", sneakerName)
+	body := fmt.Sprintf("%s is available at %s for %.2f.", sneakerName, providerName, triggered.Price)
+
+	return subject, body
+}
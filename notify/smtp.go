@@ -0,0 +1,150 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// smtpTimeout bounds a whole delivery when ctx has no earlier deadline, so a
+// stalled server cannot hold up the dispatcher.
+const smtpTimeout = 30 * time.Second
+
+// SMTPNotifier sends notifications as plain text email to the recipient.
+type SMTPNotifier struct {
+	addr string
+	host string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPNotifier sends mail through the server at addr (host:port). Empty
+// username disables authentication.
+func NewSMTPNotifier(addr string, from string, username string, password string) *SMTPNotifier {
+	host := addr
+	if i := strings.LastIndex(addr, ":"); i >= 0 {
+		host = addr[:i]
+	}
+
+	notifier := &SMTPNotifier{
+		addr: addr,
+		host: host,
+		from: from,
+	}
+
+	if username != "" {
+		notifier.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return notifier
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, notification Notification) error {
+	if notification.Recipient == "" {
+		return errors.New("notification has no email recipient")
+	}
+
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		headerValue(n.from), headerValue(notification.Recipient), headerValue(notification.Subject), notification.Body)
+
+	return n.send(ctx, notification.Recipient, []byte(message))
+}
+
+// send does what smtp.SendMail does, but dials with ctx and sets a deadline
+// on the connection.
+func (n *SMTPNotifier) send(ctx context.Context, recipient string, message []byte) error {
+	deadline := time.Now().Add(smtpTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return err
+	}
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	// unblock reads and writes as soon as ctx is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	// report a cancellation rather than the I/O error it caused
+	fail := func(err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return fail(err)
+	}
+
+	defer client.Close()
+
+	if err := n.converse(client, recipient, message); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (n *SMTPNotifier) converse(client *smtp.Client, recipient string, message []byte) error {
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return err
+		}
+	}
+
+	if n.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support AUTH")
+		}
+		if err := client.Auth(n.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+
+	if err := client.Rcpt(recipient); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// headerValue keeps values such as a sneaker name in the subject from
+// adding headers of their own.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
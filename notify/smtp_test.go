@@ -0,0 +1,189 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpServer is a minimal local SMTP server that accepts a single message.
+type smtpServer struct {
+	listener net.Listener
+	// stall makes the server accept connections without ever answering.
+	stall bool
+
+	from     string
+	rcpt     string
+	data     string
+	finished chan struct{}
+}
+
+func newSMTPServer(t *testing.T, stall bool) *smtpServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	server := &smtpServer{listener: listener, stall: stall, finished: make(chan struct{})}
+	t.Cleanup(func() { listener.Close() })
+
+	go server.serve()
+
+	return server
+}
+
+func (s *smtpServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *smtpServer) serve() {
+	defer close(s.finished)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	if s.stall {
+		// hold the connection until the client gives up
+		conn.Read(make([]byte, 1))
+		return
+	}
+
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost ready")
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 localhost")
+		case "MAIL":
+			s.from = line
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			s.rcpt = line
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 end with .")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.data = string(data)
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPNotifierSendsMail(t *testing.T) {
+	server := newSMTPServer(t, false)
+	notifier := NewSMTPNotifier(server.addr(), "alerts@kys.test", "", "")
+
+	notification := Notification{
+		Recipient: "greta@test.com",
+		Subject:   "Price alert: Dunk\r\nBcc: victim@example.com",
+		Body:      "Dunk Low is available at Shop for 99.00.",
+	}
+
+	if err := notifier.Notify(context.Background(), notification); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	<-server.finished
+
+	if server.from != "MAIL FROM:<alerts@kys.test>" || !strings.HasPrefix(server.rcpt, "RCPT TO:<greta@test.com>") {
+		t.Errorf("envelope = %q %q", server.from, server.rcpt)
+	}
+
+	header, body, _ := strings.Cut(server.data, "\n\n")
+
+	reader := textproto.NewReader(bufio.NewReader(strings.NewReader(header + "\n\n")))
+	headers, err := reader.ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("parse headers of %q: %v", server.data, err)
+	}
+
+	if _, ok := headers["Bcc"]; ok {
+		t.Errorf("subject injected a Bcc header: %q", header)
+	}
+
+	if subject := headers.Get("Subject"); subject != "Price alert: Dunk  Bcc: victim@example.com" {
+		t.Errorf("Subject = %q", subject)
+	}
+
+	if strings.TrimSpace(body) != notification.Body {
+		t.Errorf("body = %q, want %q", body, notification.Body)
+	}
+}
+
+func TestSMTPNotifierRequiresRecipient(t *testing.T) {
+	notifier := NewSMTPNotifier("127.0.0.1:1", "alerts@kys.test", "", "")
+
+	if err := notifier.Notify(context.Background(), Notification{}); err == nil {
+		t.Fatal("Notify returned no error")
+	}
+}
+
+func TestSMTPNotifierGivesUpOnStalledServer(t *testing.T) {
+	// The connection deadline is the ctx deadline, so either may fire first.
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+		want []error
+	}{
+		{"deadline", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 100*time.Millisecond)
+		}, []error{context.DeadlineExceeded, os.ErrDeadlineExceeded}},
+		{"cancel", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(100*time.Millisecond, cancel)
+			return ctx, cancel
+		}, []error{context.Canceled}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newSMTPServer(t, true)
+			notifier := NewSMTPNotifier(server.addr(), "alerts@kys.test", "", "")
+
+			ctx, cancel := test.ctx()
+			defer cancel()
+
+			start := time.Now()
+			err := notifier.Notify(ctx, Notification{Recipient: "greta@test.com", Subject: "s", Body: "b"})
+
+			matched := false
+			for _, want := range test.want {
+				matched = matched || errors.Is(err, want)
+			}
+
+			if !matched {
+				t.Errorf("err = %v, want one of %v", err, test.want)
+			}
+
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Notify took %v on a stalled server", elapsed)
+			}
+		})
+	}
+}
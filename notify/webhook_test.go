@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookNotifierPostsJSON(t *testing.T) {
+	var received Notification
+	var contentType string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.Client(), server.URL)
+	notification := Notification{Id: 7, UserId: 3, Channel: ChannelWebhook, Subject: "Price alert: Dunk Low", Body: "Dunk Low is available"}
+
+	if err := notifier.Notify(context.Background(), notification); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+
+	if received.Id != 7 || received.UserId != 3 || received.Subject != notification.Subject || received.Body != notification.Body {
+		t.Errorf("payload = %+v, want %+v", received, notification)
+	}
+}
+
+func TestWebhookNotifierRejectsErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.Client(), server.URL)
+
+	err := notifier.Notify(context.Background(), Notification{Id: 1})
+	if err == nil || !strings.Contains(err.Error(), "unexpected status 502") {
+		t.Fatalf("err = %v, want unexpected status 502", err)
+	}
+}
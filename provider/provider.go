@@ -2,5 +2,5 @@ package provider
 
 type ProviderInformation struct {
 	Id           float64 `json:"id"`
-	ProviderName string  `json:"providerName"`
+	ProviderName string  `json:"providerName" binding:"required,max=100"`
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"github.com/Gretamass/kys-backend/auth"
+	"github.com/Gretamass/kys-backend/config"
+	"github.com/Gretamass/kys-backend/metrics"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// router registers the middleware and every route of the API. It expects
+// registerValidations to have run.
+func (s *server) router(cfg config.ServerConfig, m *metrics.Metrics) (*gin.Engine, error) {
+	r := gin.New()
+	r.Use(requestId(), requestLogger(), requestMetrics(m), recovery())
+
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	// CORS has to be registered before the routes to apply to them
+	corsConfig := cors.DefaultConfig()
+	if len(cfg.CORSOrigins) == 1 && cfg.CORSOrigins[0] == "*" {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		corsConfig.AllowOrigins = cfg.CORSOrigins
+	}
+	corsConfig.AddAllowHeaders("Authorization")
+	r.Use(cors.New(corsConfig))
+	r.Use(errorHandler())
+
+	if path := cfg.MetricsPath; path != "" {
+		r.GET(path, gin.WrapH(m.Handler()))
+	}
+
+	// routes of a store or worker the server was built without answer 501
+	notifications := available(s.notifications != nil)
+	catalog := available(s.catalog != nil)
+	scrapes := available(s.catalog != nil && s.scraper != nil)
+
+	authRequired := s.authRequired()
+	adminOnly := requireRole(auth.RoleAdmin)
+	selfOrAdmin := requireSelfOrAdmin()
+
+	userRouter := r.Group("/user")
+	{
+		userRouter.GET("/", authRequired, adminOnly, s.getUsers)
+		userRouter.GET("/:id", authRequired, selfOrAdmin, s.getUserById)
+		userRouter.POST("/", s.createUser)
+		userRouter.PATCH("/:id", authRequired, selfOrAdmin, s.updateUser)
+		userRouter.DELETE("/:id", authRequired, adminOnly, s.deleteUser)
+		userRouter.GET("/:id/alerts", authRequired, selfOrAdmin, s.getUserAlerts)
+		userRouter.GET("/:id/alerts/triggered", authRequired, selfOrAdmin, s.getUserTriggeredAlerts)
+		userRouter.POST("/:id/alerts", authRequired, selfOrAdmin, s.createUserAlert)
+		userRouter.PATCH("/:id/alerts/:alertId", authRequired, selfOrAdmin, s.updateUserAlert)
+		userRouter.DELETE("/:id/alerts/:alertId", authRequired, selfOrAdmin, s.deleteUserAlert)
+		userRouter.GET("/:id/inbox", authRequired, selfOrAdmin, notifications(s.getUserInbox))
+	}
+
+	adminRouter := r.Group("/admin", authRequired, adminOnly)
+	{
+		adminRouter.GET("/", s.getAdmins)
+		adminRouter.GET("/:id", s.getAdminById)
+		adminRouter.POST("/", s.createAdmin)
+		adminRouter.PATCH("/:id", s.updateAdmin)
+		adminRouter.DELETE("/:id", s.deleteAdmin)
+		adminRouter.GET("/notifications/dead", notifications(s.getDeadNotifications))
+		adminRouter.POST("/notifications/:id/retry", notifications(s.retryNotification))
+	}
+
+	loginRouter := r.Group("/login")
+	{
+		loginRouter.POST("/", s.loginUser)
+		loginRouter.POST("/admin", s.loginAdmin)
+		loginRouter.GET("/me", authRequired, s.getCurrentUser)
+		loginRouter.POST("/refresh", s.refreshTokens)
+		loginRouter.POST("/logout", s.logout)
+	}
+
+	sneakerRouter := r.Group("/sneaker")
+	{
+		sneakerRouter.GET("/", s.getSneakers)
+		sneakerRouter.GET("/info", s.getSneakersInfo)
+		sneakerRouter.GET("/:id", s.getSneakerInfo)
+		sneakerRouter.GET("/availability", catalog(s.getSneakersAvailability))
+		sneakerRouter.GET("/search", catalog(s.searchSneakers))
+		sneakerRouter.GET("/offers/best", catalog(s.getBestOffers))
+		sneakerRouter.GET("/:id/offers", catalog(s.getSneakerOffers))
+		sneakerRouter.GET("/:id/scrapper", catalog(s.getSneakerScrapper))
+		sneakerRouter.POST("/:id/scrapper/run", authRequired, adminOnly, scrapes(s.runSneakerScrapper))
+		sneakerRouter.GET("/:id/history", catalog(s.getSneakerHistory))
+		sneakerRouter.POST("/", authRequired, adminOnly, s.createSneaker)
+		sneakerRouter.PATCH("/:id", authRequired, adminOnly, s.updateSneaker)
+		sneakerRouter.DELETE("/:id", authRequired, adminOnly, s.deleteSneaker)
+	}
+
+	providerRouter := r.Group("/provider")
+	{
+		providerRouter.GET("/", s.getProviders)
+		providerRouter.GET("/:id", s.getProviderById)
+		providerRouter.POST("/", authRequired, adminOnly, s.createProvider)
+		providerRouter.PATCH("/:id", authRequired, adminOnly, s.updateProvider)
+		providerRouter.DELETE("/:id", authRequired, adminOnly, s.deleteProvider)
+	}
+
+	r.NoRoute(func(c *gin.Context) {
+		respondError(c, http.StatusNotFound, "route not found")
+	})
+
+	return r, nil
+}
+
+// available returns its handler unchanged when configured is set and
+// replaces it with a 501 otherwise.
+func available(configured bool) func(handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(handler gin.HandlerFunc) gin.HandlerFunc {
+		if configured {
+			return handler
+		}
+
+		return func(c *gin.Context) {
+			respondError(c, http.StatusNotImplemented, "not implemented by this server")
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Gretamass/kys-backend/sneaker"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrPriceNotFound = errors.New("price not found on provider page")
+
+// maxPageSize caps how much of a provider page is read.
+const maxPageSize = 5 << 20
+
+// HTTPAdapterConfig describes how to scrape a provider whose product search
+// page can be matched with regular expressions.
+type HTTPAdapterConfig struct {
+	ProviderId int `json:"providerId"`
+	// SearchURL contains a single %s which is replaced with the escaped
+	// search_for value of the scrapper row.
+	SearchURL string `json:"searchUrl"`
+	// PricePattern must have one capture group matching the price.
+	PricePattern string `json:"pricePattern"`
+	// AvailablePattern marks the product as available when it matches.
+	AvailablePattern string `json:"availablePattern"`
+	// UnavailablePattern marks the product as unavailable when it matches.
+	// It is only used when AvailablePattern is empty.
+	UnavailablePattern string `json:"unavailablePattern"`
+	// SizePattern is matched repeatedly, once per size listed on the page.
+	// It needs a named group "size" and may have a "price" group and an
+	// "unavailable" group that is non-empty for sizes out of stock.
+	SizePattern string `json:"sizePattern"`
+	// SizeSystem is the system the page lists sizes in, us by default.
+	SizeSystem string `json:"sizeSystem"`
+}
+
+// HTTPAdapter fetches a provider search page and extracts price and
+// availability with regular expressions.
+type HTTPAdapter struct {
+	client      *http.Client
+	searchURL   string
+	price       *regexp.Regexp
+	available   *regexp.Regexp
+	unavailable *regexp.Regexp
+	size        *regexp.Regexp
+	sizeSystem  string
+}
+
+func NewHTTPAdapter(client *http.Client, config HTTPAdapterConfig) (*HTTPAdapter, error) {
+	if !strings.Contains(config.SearchURL, "%s") {
+		return nil, fmt.Errorf("provider %d: searchUrl must contain %%s", config.ProviderId)
+	}
+
+	price, err := regexp.Compile(config.PricePattern)
+	if err != nil {
+		return nil, fmt.Errorf("provider %d: pricePattern: %w", config.ProviderId, err)
+	}
+
+	if price.NumSubexp() != 1 {
+		return nil, fmt.Errorf("provider %d: pricePattern must have exactly one capture group", config.ProviderId)
+	}
+
+	adapter := &HTTPAdapter{
+		client:    client,
+		searchURL: config.SearchURL,
+		price:     price,
+	}
+
+	if config.AvailablePattern != "" {
+		if adapter.available, err = regexp.Compile(config.AvailablePattern); err != nil {
+			return nil, fmt.Errorf("provider %d: availablePattern: %w", config.ProviderId, err)
+		}
+	}
+
+	if config.UnavailablePattern != "" {
+		if adapter.unavailable, err = regexp.Compile(config.UnavailablePattern); err != nil {
+			return nil, fmt.Errorf("provider %d: unavailablePattern: %w", config.ProviderId, err)
+		}
+	}
+
+	if config.SizePattern != "" {
+		if adapter.size, err = regexp.Compile(config.SizePattern); err != nil {
+			return nil, fmt.Errorf("provider %d: sizePattern: %w", config.ProviderId, err)
+		}
+
+		if adapter.size.SubexpIndex("size") < 0 {
+			return nil, fmt.Errorf("provider %d: sizePattern must have a named group \"size\"", config.ProviderId)
+		}
+
+		adapter.sizeSystem = config.SizeSystem
+		if adapter.sizeSystem == "" {
+			adapter.sizeSystem = sneaker.SizeUS
+		}
+
+		if err := sneaker.CheckSizeSystem(adapter.sizeSystem); err != nil {
+			return nil, fmt.Errorf("provider %d: sizeSystem: %w", config.ProviderId, err)
+		}
+	}
+
+	return adapter, nil
+}
+
+func (a *HTTPAdapter) Scrape(ctx context.Context, scrapper sneaker.Scrapper) (Result, error) {
+	page, err := a.fetch(ctx, fmt.Sprintf(a.searchURL, url.QueryEscape(scrapper.SearchFor)))
+	if err != nil {
+		return Result{}, err
+	}
+
+	return a.extract(page)
+}
+
+func (a *HTTPAdapter) fetch(ctx context.Context, pageURL string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := a.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: unexpected status %s", pageURL, response.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(response.Body, maxPageSize))
+}
+
+func (a *HTTPAdapter) extract(page []byte) (Result, error) {
+	result := Result{Available: true}
+
+	switch {
+	case a.available != nil:
+		result.Available = a.available.Match(page)
+	case a.unavailable != nil:
+		result.Available = !a.unavailable.Match(page)
+	}
+
+	match := a.price.FindSubmatch(page)
+	if match == nil {
+		// Out of stock products often have no price shown at all
+		if !result.Available {
+			if a.size != nil {
+				result.Sizes = make([]sneaker.SizeAvailability, 0)
+			}
+			return result, nil
+		}
+		return Result{}, ErrPriceNotFound
+	}
+
+	price, err := parsePrice(string(match[1]))
+	if err != nil {
+		return Result{}, err
+	}
+
+	result.Price = price
+
+	if a.size != nil {
+		if result.Sizes, err = a.extractSizes(page, result.Price); err != nil {
+			return Result{}, err
+		}
+	}
+
+	return result, nil
+}
+
+// extractSizes reads every size listed on the page. Sizes missing from the
+// size chart are skipped and sizes without their own price get price.
+func (a *HTTPAdapter) extractSizes(page []byte, price float32) ([]sneaker.SizeAvailability, error) {
+	sizes := make([]sneaker.SizeAvailability, 0)
+	priceGroup := a.size.SubexpIndex("price")
+	unavailableGroup := a.size.SubexpIndex("unavailable")
+
+	for _, match := range a.size.FindAllSubmatch(page, -1) {
+		us, err := sneaker.ParseSize(string(match[a.size.SubexpIndex("size")]), a.sizeSystem)
+		if err != nil {
+			continue
+		}
+
+		sizePrice := price
+		if priceGroup >= 0 && len(match[priceGroup]) > 0 {
+			if sizePrice, err = parsePrice(string(match[priceGroup])); err != nil {
+				return nil, err
+			}
+		}
+
+		available := unavailableGroup < 0 || len(match[unavailableGroup]) == 0
+
+		sizes = append(sizes, sneaker.NewSizeAvailability(us, sizePrice, available))
+	}
+
+	return sizes, nil
+}
+
+// parsePrice accepts prices such as "249.99", "249,99" and "1 299,00".
+func parsePrice(raw string) (float32, error) {
+	price := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\u00a0' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+
+	if comma := strings.LastIndex(price, ","); comma > strings.LastIndex(price, ".") {
+		price = strings.ReplaceAll(price[:comma], ".", "") + "." + price[comma+1:]
+	} else {
+		price = strings.ReplaceAll(price, ",", "")
+	}
+
+	value, err := strconv.ParseFloat(price, 32)
+	if err != nil {
+		return 0, fmt.Errorf("parse price %q: %w", raw, err)
+	}
+
+	return float32(value), nil
+}
+
+// LoadHTTPAdapterConfigs reads a JSON array of HTTPAdapterConfig from path.
+func LoadHTTPAdapterConfigs(path string) ([]HTTPAdapterConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []HTTPAdapterConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return configs, nil
+}
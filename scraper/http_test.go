@@ -0,0 +1,220 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Gretamass/kys-backend/sneaker"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fixtureServer serves the page for every search and records the last
+// search query.
+func fixtureServer(t *testing.T, status int, page string) (*httptest.Server, *string) {
+	t.Helper()
+
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("q")
+		w.WriteHeader(status)
+		fmt.Fprint(w, page)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &query
+}
+
+func newTestAdapter(t *testing.T, server *httptest.Server, config HTTPAdapterConfig) *HTTPAdapter {
+	t.Helper()
+
+	config.ProviderId = 1
+	config.SearchURL = server.URL + "/search?q=%s"
+	if config.PricePattern == "" {
+		config.PricePattern = `<span class="price">([^<]+) €</span>`
+	}
+
+	adapter, err := NewHTTPAdapter(server.Client(), config)
+	if err != nil {
+		t.Fatalf("NewHTTPAdapter: %v", err)
+	}
+
+	return adapter
+}
+
+func TestHTTPAdapterScrape(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    HTTPAdapterConfig
+		page      string
+		price     float32
+		available bool
+	}{
+		{
+			name:      "decimal comma",
+			page:      `<span class="price">249,99 €</span>`,
+			price:     249.99,
+			available: true,
+		},
+		{
+			name:      "thousands separator",
+			page:      `<span class="price">1 299,00 €</span>`,
+			price:     1299,
+			available: true,
+		},
+		{
+			name:      "available pattern matches",
+			config:    HTTPAdapterConfig{AvailablePattern: `add-to-cart`},
+			page:      `<span class="price">120.00 €</span><button class="add-to-cart">`,
+			price:     120,
+			available: true,
+		},
+		{
+			name:      "available pattern missing",
+			config:    HTTPAdapterConfig{AvailablePattern: `add-to-cart`},
+			page:      `<span class="price">120.00 €</span>`,
+			price:     120,
+			available: false,
+		},
+		{
+			name:      "unavailable pattern matches",
+			config:    HTTPAdapterConfig{UnavailablePattern: `sold out`},
+			page:      `<span class="price">99,50 €</span><p>sold out</p>`,
+			price:     99.5,
+			available: false,
+		},
+		{
+			name:      "unavailable without price",
+			config:    HTTPAdapterConfig{UnavailablePattern: `sold out`},
+			page:      `<p>sold out</p>`,
+			available: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, query := fixtureServer(t, http.StatusOK, test.page)
+			adapter := newTestAdapter(t, server, test.config)
+
+			result, err := adapter.Scrape(context.Background(), sneaker.Scrapper{SearchFor: "air jordan 1"})
+			if err != nil {
+				t.Fatalf("Scrape: %v", err)
+			}
+
+			if *query != "air jordan 1" {
+				t.Errorf("search query = %q, want %q", *query, "air jordan 1")
+			}
+
+			if result.Price != test.price || result.Available != test.available {
+				t.Errorf("result = %+v, want price %v available %v", result, test.price, test.available)
+			}
+		})
+	}
+}
+
+func TestHTTPAdapterScrapePriceNotFound(t *testing.T) {
+	server, _ := fixtureServer(t, http.StatusOK, `<p>no results</p>`)
+	adapter := newTestAdapter(t, server, HTTPAdapterConfig{})
+
+	_, err := adapter.Scrape(context.Background(), sneaker.Scrapper{SearchFor: "unknown"})
+	if !errors.Is(err, ErrPriceNotFound) {
+		t.Fatalf("err = %v, want ErrPriceNotFound", err)
+	}
+}
+
+func TestHTTPAdapterScrapeUnexpectedStatus(t *testing.T) {
+	server, _ := fixtureServer(t, http.StatusServiceUnavailable, `<span class="price">10 €</span>`)
+	adapter := newTestAdapter(t, server, HTTPAdapterConfig{})
+
+	_, err := adapter.Scrape(context.Background(), sneaker.Scrapper{SearchFor: "x"})
+	if err == nil || !strings.Contains(err.Error(), "unexpected status 503") {
+		t.Fatalf("err = %v, want unexpected status 503", err)
+	}
+}
+
+func TestHTTPAdapterScrapeSizes(t *testing.T) {
+	page := `<span class="price">150,00 €</span>
+<li data-size="42">160,00</li>
+<li data-size="43" class="sold-out"></li>
+<li data-size="99"></li>`
+
+	server, _ := fixtureServer(t, http.StatusOK, page)
+	adapter := newTestAdapter(t, server, HTTPAdapterConfig{
+		SizePattern: `<li data-size="(?P<size>[\d.]+)"(?P<unavailable> class="sold-out")?>(?P<price>[\d,]*)</li>`,
+		SizeSystem:  sneaker.SizeEU,
+	})
+
+	result, err := adapter.Scrape(context.Background(), sneaker.Scrapper{SearchFor: "x"})
+	if err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+
+	// EU 99 is not on the size chart and is skipped
+	want := []sneaker.SizeAvailability{
+		sneaker.NewSizeAvailability(8.5, 160, true),
+		sneaker.NewSizeAvailability(9.5, 150, false),
+	}
+
+	if len(result.Sizes) != len(want) {
+		t.Fatalf("sizes = %+v, want %+v", result.Sizes, want)
+	}
+
+	for i := range want {
+		if result.Sizes[i] != want[i] {
+			t.Errorf("sizes[%d] = %+v, want %+v", i, result.Sizes[i], want[i])
+		}
+	}
+}
+
+func TestNewHTTPAdapterRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config HTTPAdapterConfig
+	}{
+		{"search url without placeholder", HTTPAdapterConfig{SearchURL: "http://shop", PricePattern: `(\d+)`}},
+		{"price pattern without group", HTTPAdapterConfig{SearchURL: "http://shop?q=%s", PricePattern: `\d+`}},
+		{"size pattern without size group", HTTPAdapterConfig{SearchURL: "http://shop?q=%s", PricePattern: `(\d+)`, SizePattern: `\d+`}},
+		{"unknown size system", HTTPAdapterConfig{SearchURL: "http://shop?q=%s", PricePattern: `(\d+)`, SizePattern: `(?P<size>\d+)`, SizeSystem: "jp"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewHTTPAdapter(http.DefaultClient, test.config); err == nil {
+				t.Fatal("NewHTTPAdapter returned no error")
+			}
+		})
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want float32
+	}{
+		{"249.99", 249.99},
+		{"249,99", 249.99},
+		{"1 299,00", 1299},
+		{"1 299,00", 1299},
+		{"1.299,00", 1299},
+		{"1,299.00", 1299},
+		{" 80 ", 80},
+	}
+
+	for _, test := range tests {
+		got, err := parsePrice(test.raw)
+		if err != nil {
+			t.Errorf("parsePrice(%q): %v", test.raw, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("parsePrice(%q) = %v, want %v", test.raw, got, test.want)
+		}
+	}
+
+	if _, err := parsePrice("free"); err == nil {
+		t.Error(`parsePrice("free") returned no error`)
+	}
+}
@@ -0,0 +1,270 @@
+package scraper
+
+import (
+	"context"
+	"github.com/Gretamass/kys-backend/sneaker"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RunFunc scrapes a single scrapper row. Engine.Run is the usual implementation.
+type RunFunc func(ctx context.Context, scrapper sneaker.Scrapper) (sneaker.Availability, error)
+
+// Source lists every availability_scrappers row.
+type Source interface {
+	GetScrappers() ([]sneaker.Scrapper, error)
+}
+
+type SchedulerConfig struct {
+	// Interval between two walks over all scrapper rows.
+	Interval time.Duration
+	// Concurrency is the number of scrappers run at once per provider.
+	Concurrency int
+	// ProviderConcurrency overrides Concurrency for single providers.
+	ProviderConcurrency map[int]int
+	// BaseBackoff is the delay before retrying a scrapper after its first
+	// failure. It doubles on every further failure up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Scheduler periodically runs all scrapper rows and keeps the last run
+// status of each one.
+type Scheduler struct {
+	source Source
+	run    RunFunc
+	config SchedulerConfig
+
+	mu       sync.Mutex
+	statuses map[int]sneaker.ScrapperStatus
+	limits   map[int]chan struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewScheduler(source Source, run RunFunc, config SchedulerConfig) *Scheduler {
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+
+	return &Scheduler{
+		source:   source,
+		run:      run,
+		config:   config,
+		statuses: make(map[int]sneaker.ScrapperStatus),
+		limits:   make(map[int]chan struct{}),
+	}
+}
+
+// Start walks the scrapper rows immediately and then every Interval until
+// ctx is cancelled or Stop is called. In between, it wakes up when the
+// backoff of a failed row runs out and retries just the failed rows that are
+// due.
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+
+		var retry *time.Timer
+		defer func() {
+			if retry != nil {
+				retry.Stop()
+			}
+		}()
+
+		retriesOnly := false
+
+		for {
+			s.runOnce(ctx, retriesOnly)
+
+			if retry != nil {
+				retry.Stop()
+			}
+
+			var retryC <-chan time.Time
+			if at, ok := s.nextRetry(time.Now()); ok {
+				retry = time.NewTimer(time.Until(at))
+				retryC = retry.C
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				retriesOnly = false
+			case <-retryC:
+				retriesOnly = true
+			}
+		}
+	}()
+}
+
+// Stop cancels running scrapes and waits for them to return.
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+
+	s.cancel()
+	<-s.done
+}
+
+// Status returns the last run status of a scrapper row.
+func (s *Scheduler) Status(scrapperId int) (sneaker.ScrapperStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, ok := s.statuses[scrapperId]
+	return status, ok
+}
+
+// runOnce runs every due scrapper row, or only the due rows that are backing
+// off after a failure when retriesOnly is set.
+func (s *Scheduler) runOnce(ctx context.Context, retriesOnly bool) {
+	scrappers, err := s.source.GetScrappers()
+	if err != nil {
+		slog.Error("load scrappers", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	for _, scrapper := range scrappers {
+		if !s.due(scrapper.Id, time.Now(), retriesOnly) {
+			continue
+		}
+
+		// Every row waits for its provider's slot in its own goroutine so a
+		// saturated provider does not hold back the others.
+		wg.Add(1)
+		go func(scrapper sneaker.Scrapper) {
+			defer wg.Done()
+
+			limit := s.limit(scrapper.ProviderId)
+
+			select {
+			case <-ctx.Done():
+				return
+			case limit <- struct{}{}:
+			}
+			defer func() { <-limit }()
+
+			_, err := s.run(ctx, scrapper)
+			s.record(scrapper.Id, err)
+		}(scrapper)
+	}
+
+	wg.Wait()
+}
+
+func (s *Scheduler) due(scrapperId int, now time.Time, retriesOnly bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, ok := s.statuses[scrapperId]
+	if !ok || status.NextRunAt == nil {
+		return !retriesOnly
+	}
+
+	return !now.Before(*status.NextRunAt)
+}
+
+// nextRetry returns the earliest time after now at which a failed row is due
+// again.
+func (s *Scheduler) nextRetry(now time.Time) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next time.Time
+	for _, status := range s.statuses {
+		if status.NextRunAt == nil || !status.NextRunAt.After(now) {
+			continue
+		}
+		if next.IsZero() || status.NextRunAt.Before(next) {
+			next = *status.NextRunAt
+		}
+	}
+
+	return next, !next.IsZero()
+}
+
+// limit returns the semaphore bounding concurrent scrapes of a provider.
+func (s *Scheduler) limit(providerId int) chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit, ok := s.limits[providerId]
+	if !ok {
+		size := s.config.Concurrency
+		if override, ok := s.config.ProviderConcurrency[providerId]; ok && override > 0 {
+			size = override
+		}
+
+		limit = make(chan struct{}, size)
+		s.limits[providerId] = limit
+	}
+
+	return limit
+}
+
+func (s *Scheduler) record(scrapperId int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	status := s.statuses[scrapperId]
+	status.LastRunAt = &now
+
+	if err == nil {
+		status.LastSuccessAt = &now
+		status.LastError = ""
+		status.ConsecutiveFailures = 0
+		status.NextRunAt = nil
+	} else {
+		status.LastError = err.Error()
+		status.ConsecutiveFailures++
+		next := now.Add(s.backoff(status.ConsecutiveFailures))
+		status.NextRunAt = &next
+
+		slog.Warn("scrape failed", "scrapper_id", scrapperId, "failures", status.ConsecutiveFailures,
+			"next_run_at", next, "error", err)
+	}
+
+	s.statuses[scrapperId] = status
+}
+
+// backoff doubles BaseBackoff per consecutive failure and spreads retries by
+// +/-50% so failing providers are not hit in bursts. The result never
+// exceeds MaxBackoff.
+func (s *Scheduler) backoff(failures int) time.Duration {
+	delay := s.config.BaseBackoff
+	for i := 1; i < failures && i < 32; i++ {
+		delay *= 2
+		if s.config.MaxBackoff > 0 && delay >= s.config.MaxBackoff {
+			break
+		}
+	}
+
+	if s.config.MaxBackoff > 0 && delay > s.config.MaxBackoff {
+		delay = s.config.MaxBackoff
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	jittered := delay/2 + time.Duration(rand.Int63n(int64(delay)))
+	if s.config.MaxBackoff > 0 && jittered > s.config.MaxBackoff {
+		jittered = s.config.MaxBackoff
+	}
+
+	return jittered
+}
@@ -0,0 +1,141 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"github.com/Gretamass/kys-backend/sneaker"
+	"sync"
+	"testing"
+	"time"
+)
+
+type staticSource []sneaker.Scrapper
+
+func (s staticSource) GetScrappers() ([]sneaker.Scrapper, error) {
+	return s, nil
+}
+
+func TestSchedulerProvidersProceedIndependently(t *testing.T) {
+	source := staticSource{
+		{Id: 1, ProviderId: 1},
+		{Id: 2, ProviderId: 1},
+		{Id: 3, ProviderId: 2},
+	}
+
+	release := make(chan struct{})
+	otherProviderRan := make(chan struct{})
+
+	run := func(ctx context.Context, scrapper sneaker.Scrapper) (sneaker.Availability, error) {
+		if scrapper.ProviderId == 2 {
+			close(otherProviderRan)
+			return sneaker.Availability{}, nil
+		}
+
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return sneaker.Availability{}, nil
+	}
+
+	scheduler := NewScheduler(source, run, SchedulerConfig{Interval: time.Hour, Concurrency: 1})
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.runOnce(context.Background(), false)
+		close(done)
+	}()
+
+	select {
+	case <-otherProviderRan:
+	case <-time.After(2 * time.Second):
+		t.Fatal("provider 2 did not run while provider 1 was saturated")
+	}
+
+	close(release)
+	<-done
+
+	for _, id := range []int{1, 2, 3} {
+		if _, ok := scheduler.Status(id); !ok {
+			t.Errorf("scrapper %d has no status", id)
+		}
+	}
+}
+
+func TestSchedulerBacksOffFailures(t *testing.T) {
+	source := staticSource{{Id: 1, ProviderId: 1}}
+
+	runs := 0
+	run := func(ctx context.Context, scrapper sneaker.Scrapper) (sneaker.Availability, error) {
+		runs++
+		return sneaker.Availability{}, errors.New("provider down")
+	}
+
+	scheduler := NewScheduler(source, run, SchedulerConfig{Interval: time.Hour, BaseBackoff: time.Hour})
+
+	scheduler.runOnce(context.Background(), false)
+	scheduler.runOnce(context.Background(), false)
+
+	if runs != 1 {
+		t.Errorf("runs = %d, want the failed scrapper to wait for its backoff", runs)
+	}
+
+	status, ok := scheduler.Status(1)
+	if !ok || status.ConsecutiveFailures != 1 || status.NextRunAt == nil || status.LastError != "provider down" {
+		t.Errorf("status = %+v, want one failure with a next run", status)
+	}
+}
+
+func TestSchedulerRetriesWhenBackoffRunsOut(t *testing.T) {
+	source := staticSource{{Id: 1, ProviderId: 1}, {Id: 2, ProviderId: 2}}
+
+	var mu sync.Mutex
+	runs := make(map[int]int)
+	retried := make(chan struct{})
+
+	run := func(ctx context.Context, scrapper sneaker.Scrapper) (sneaker.Availability, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		runs[scrapper.Id]++
+		if scrapper.Id != 1 {
+			return sneaker.Availability{}, nil
+		}
+		if runs[1] == 1 {
+			return sneaker.Availability{}, errors.New("provider down")
+		}
+
+		close(retried)
+		return sneaker.Availability{}, nil
+	}
+
+	// The interval is far away, so only the backoff can bring the retry.
+	scheduler := NewScheduler(source, run, SchedulerConfig{Interval: time.Hour, BaseBackoff: 20 * time.Millisecond, MaxBackoff: 20 * time.Millisecond})
+	scheduler.Start(context.Background())
+	defer scheduler.Stop()
+
+	select {
+	case <-retried:
+	case <-time.After(2 * time.Second):
+		t.Fatal("failed scrapper was not retried before the next interval")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if runs[2] != 1 {
+		t.Errorf("healthy scrapper ran %d times, want it to wait for the interval", runs[2])
+	}
+}
+
+func TestSchedulerBackoffStaysWithinMax(t *testing.T) {
+	scheduler := NewScheduler(staticSource{}, nil, SchedulerConfig{BaseBackoff: time.Minute, MaxBackoff: 10 * time.Minute})
+
+	for failures := 1; failures <= 10; failures++ {
+		for i := 0; i < 100; i++ {
+			if delay := scheduler.backoff(failures); delay <= 0 || delay > 10*time.Minute {
+				t.Fatalf("backoff(%d) = %v, want it within (0, 10m]", failures, delay)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Gretamass/kys-backend/sneaker"
+	"sync"
+	"time"
+)
+
+var ErrNoAdapter = errors.New("no scraper adapter registered for provider")
+
+// Result is what an adapter extracted from a provider page.
+type Result struct {
+	Price     float32
+	Available bool
+	// Sizes is nil when the adapter does not report sizes.
+	Sizes []sneaker.SizeAvailability
+}
+
+// Adapter scrapes a single provider. It receives the availability_scrappers
+// row describing what to search for.
+type Adapter interface {
+	Scrape(ctx context.Context, scrapper sneaker.Scrapper) (Result, error)
+}
+
+// Store persists scraped availability into provider_information.
+type Store interface {
+	UpsertProviderInformation(availability sneaker.Availability) error
+}
+
+// RunObserver is told about every finished Run. err is nil on success.
+type RunObserver func(scrapper sneaker.Scrapper, err error, elapsed time.Duration)
+
+// Engine runs scrapper rows through the adapter registered for their provider
+// and stores the outcome.
+type Engine struct {
+	store Store
+
+	mu        sync.RWMutex
+	adapters  map[int]Adapter
+	observers []RunObserver
+}
+
+func NewEngine(store Store) *Engine {
+	return &Engine{
+		store:    store,
+		adapters: make(map[int]Adapter),
+	}
+}
+
+// Register sets the adapter used for scrapper rows of providerId.
+func (e *Engine) Register(providerId int, adapter Adapter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.adapters[providerId] = adapter
+}
+
+// Observe adds an observer called after every Run.
+func (e *Engine) Observe(observer RunObserver) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.observers = append(e.observers, observer)
+}
+
+func (e *Engine) adapter(providerId int) (Adapter, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	adapter, ok := e.adapters[providerId]
+	return adapter, ok
+}
+
+// Run scrapes the provider page for scrapper and upserts the result into
+// provider_information.
+func (e *Engine) Run(ctx context.Context, scrapper sneaker.Scrapper) (sneaker.Availability, error) {
+	start := time.Now()
+	availability, err := e.run(ctx, scrapper)
+
+	e.mu.RLock()
+	observers := e.observers
+	e.mu.RUnlock()
+
+	for _, observer := range observers {
+		observer(scrapper, err, time.Since(start))
+	}
+
+	return availability, err
+}
+
+func (e *Engine) run(ctx context.Context, scrapper sneaker.Scrapper) (sneaker.Availability, error) {
+	adapter, ok := e.adapter(scrapper.ProviderId)
+	if !ok {
+		return sneaker.Availability{}, fmt.Errorf("%w: %d", ErrNoAdapter, scrapper.ProviderId)
+	}
+
+	result, err := adapter.Scrape(ctx, scrapper)
+	if err != nil {
+		return sneaker.Availability{}, fmt.Errorf("scrapper %d: %w", scrapper.Id, err)
+	}
+
+	availability := sneaker.Availability{
+		ProductId:  scrapper.ProductId,
+		ProviderId: scrapper.ProviderId,
+		Price:      result.Price,
+		Available:  result.Available,
+		Sizes:      result.Sizes,
+	}
+
+	if err := e.store.UpsertProviderInformation(availability); err != nil {
+		return sneaker.Availability{}, err
+	}
+
+	return availability, nil
+}
+
+// AdapterFunc lets a plain fetch function be registered as an Adapter.
+type AdapterFunc func(ctx context.Context, scrapper sneaker.Scrapper) (Result, error)
+
+func (f AdapterFunc) Scrape(ctx context.Context, scrapper sneaker.Scrapper) (Result, error) {
+	return f(ctx, scrapper)
+}
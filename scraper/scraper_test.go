@@ -0,0 +1,104 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"github.com/Gretamass/kys-backend/sneaker"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	stored []sneaker.Availability
+	err    error
+}
+
+func (s *fakeStore) UpsertProviderInformation(availability sneaker.Availability) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.stored = append(s.stored, availability)
+	return nil
+}
+
+func staticAdapter(result Result, err error) Adapter {
+	return AdapterFunc(func(ctx context.Context, scrapper sneaker.Scrapper) (Result, error) {
+		return result, err
+	})
+}
+
+func TestEngineRunStoresResult(t *testing.T) {
+	store := &fakeStore{}
+	engine := NewEngine(store)
+	engine.Register(2, staticAdapter(Result{Price: 199.99, Available: true}, nil))
+
+	var observed []error
+	engine.Observe(func(scrapper sneaker.Scrapper, err error, elapsed time.Duration) {
+		observed = append(observed, err)
+	})
+
+	availability, err := engine.Run(context.Background(), sneaker.Scrapper{Id: 5, ProductId: 1, ProviderId: 2})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := sneaker.Availability{ProductId: 1, ProviderId: 2, Price: 199.99, Available: true}
+	if availability.ProductId != want.ProductId || availability.ProviderId != want.ProviderId ||
+		availability.Price != want.Price || availability.Available != want.Available {
+		t.Errorf("availability = %+v, want %+v", availability, want)
+	}
+
+	if len(store.stored) != 1 || store.stored[0].Price != want.Price {
+		t.Errorf("stored = %+v, want one row with price %v", store.stored, want.Price)
+	}
+
+	if len(observed) != 1 || observed[0] != nil {
+		t.Errorf("observed = %v, want a single successful run", observed)
+	}
+}
+
+func TestEngineRunErrors(t *testing.T) {
+	errScrape := errors.New("connection refused")
+	errStore := errors.New("database is locked")
+
+	tests := []struct {
+		name     string
+		register bool
+		adapter  Adapter
+		storeErr error
+		want     error
+	}{
+		{name: "no adapter", want: ErrNoAdapter},
+		{name: "adapter fails", register: true, adapter: staticAdapter(Result{}, errScrape), want: errScrape},
+		{name: "store fails", register: true, adapter: staticAdapter(Result{Price: 10, Available: true}, nil), storeErr: errStore, want: errStore},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &fakeStore{err: test.storeErr}
+			engine := NewEngine(store)
+			if test.register {
+				engine.Register(2, test.adapter)
+			}
+
+			var observed error
+			engine.Observe(func(scrapper sneaker.Scrapper, err error, elapsed time.Duration) {
+				observed = err
+			})
+
+			_, err := engine.Run(context.Background(), sneaker.Scrapper{Id: 5, ProductId: 1, ProviderId: 2})
+			if !errors.Is(err, test.want) {
+				t.Fatalf("err = %v, want %v", err, test.want)
+			}
+
+			if !errors.Is(observed, test.want) {
+				t.Errorf("observed = %v, want %v", observed, test.want)
+			}
+
+			if len(store.stored) != 0 {
+				t.Errorf("stored = %+v, want nothing", store.stored)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package sneaker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Size systems accepted in queries and scraper configs.
+const (
+	SizeUS = "us"
+	SizeEU = "eu"
+	SizeUK = "uk"
+)
+
+// sizeChart converts men's sneaker sizes. Each row is {US, EU, UK}. Sizes
+// are stored as US sizes and converted on the way in and out.
+var sizeChart = [][3]float64{
+	{6, 38.5, 5.5},
+	{6.5, 39, 6},
+	{7, 40, 6},
+	{7.5, 40.5, 6.5},
+	{8, 41, 7},
+	{8.5, 42, 7.5},
+	{9, 42.5, 8},
+	{9.5, 43, 8.5},
+	{10, 44, 9},
+	{10.5, 44.5, 9.5},
+	{11, 45, 10},
+	{11.5, 45.5, 10.5},
+	{12, 46, 11},
+	{12.5, 47, 11.5},
+	{13, 47.5, 12},
+	{14, 48.5, 13},
+	{15, 49.5, 14},
+}
+
+// SizeAvailability is the stock of a single size at a provider.
+type SizeAvailability struct {
+	US        float64 `json:"us"`
+	EU        float64 `json:"eu,omitempty"`
+	UK        float64 `json:"uk,omitempty"`
+	Price     float32 `json:"price"`
+	Available bool    `json:"available"`
+}
+
+func sizeColumn(system string) (int, error) {
+	switch strings.ToLower(system) {
+	case SizeUS, "":
+		return 0, nil
+	case SizeEU:
+		return 1, nil
+	case SizeUK:
+		return 2, nil
+	}
+
+	return 0, fmt.Errorf("unknown size system %q", system)
+}
+
+// CheckSizeSystem reports whether system is one of the supported systems.
+func CheckSizeSystem(system string) error {
+	_, err := sizeColumn(system)
+	return err
+}
+
+// ToUS converts a size given in system to the US size it is stored as. UK
+// sizes shared by two US sizes convert to the smaller one.
+func ToUS(value float64, system string) (float64, error) {
+	column, err := sizeColumn(system)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, row := range sizeChart {
+		if row[column] == value {
+			return row[0], nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s size %v is not in the size chart", strings.ToUpper(system), value)
+}
+
+// ParseSize parses a size label such as "10", "44 1/2" or "9,5" given in
+// system and returns it as a US size.
+func ParseSize(label string, system string) (float64, error) {
+	label = strings.TrimSpace(label)
+
+	half := 0.0
+	for _, suffix := range []string{"1/2", "½"} {
+		if strings.HasSuffix(label, suffix) {
+			label = strings.TrimSpace(strings.TrimSuffix(label, suffix))
+			half = 0.5
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.Replace(label, ",", ".", 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse size %q: %w", label, err)
+	}
+
+	return ToUS(value+half, system)
+}
+
+// NewSizeAvailability fills in the EU and UK sizes for a US size.
+func NewSizeAvailability(us float64, price float32, available bool) SizeAvailability {
+	size := SizeAvailability{US: us, Price: price, Available: available}
+
+	for _, row := range sizeChart {
+		if row[0] == us {
+			size.EU = row[1]
+			size.UK = row[2]
+			break
+		}
+	}
+
+	return size
+}
@@ -1,11 +1,16 @@
 package sneaker
 
+import (
+	"sort"
+	"time"
+)
+
 type Sneaker struct {
 	Id       int    `json:"id"`
-	Name     string `json:"name"`
-	Model    string `json:"model"`
-	Brand    string `json:"brand"`
-	ImageUrl string `json:"imageUrl"`
+	Name     string `json:"name" binding:"required,max=200"`
+	Model    string `json:"model" binding:"required,max=100"`
+	Brand    string `json:"brand" binding:"required,max=100"`
+	ImageUrl string `json:"imageUrl" binding:"omitempty,url"`
 	//Description         string `json:"description"`
 	//ProviderInformation map[string]struct {
 	//	ProviderInformation
@@ -44,11 +49,12 @@ type SneakerAvailability struct {
 }
 
 type Availability struct {
-	Id         int     `json:"id"`
-	ProductId  int     `json:"productId"`
-	ProviderId int     `json:"providerId"`
-	Available  bool    `json:"available"`
-	Price      float32 `json:"price"`
+	Id         int                `json:"id"`
+	ProductId  int                `json:"productId"`
+	ProviderId int                `json:"providerId"`
+	Available  bool               `json:"available"`
+	Price      float32            `json:"price"`
+	Sizes      []SizeAvailability `json:"sizes,omitempty"`
 }
 
 type AvailabilityScrappers struct {
@@ -66,8 +72,138 @@ type ProviderInformation struct {
 }
 
 type Scrapper struct {
-	Id         int    `json:"id"`
-	ProductId  int    `json:"productId"`
-	ProviderId int    `json:"providerId"`
-	SearchFor  string `json:"search_for"`
+	Id         int             `json:"id"`
+	ProductId  int             `json:"productId"`
+	ProviderId int             `json:"providerId"`
+	SearchFor  string          `json:"search_for"`
+	Status     *ScrapperStatus `json:"status,omitempty"`
+}
+
+type ScrapperStatus struct {
+	LastRunAt           *time.Time `json:"lastRunAt,omitempty"`
+	LastSuccessAt       *time.Time `json:"lastSuccessAt,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	NextRunAt           *time.Time `json:"nextRunAt,omitempty"`
+}
+
+type PriceHistory struct {
+	ProviderId int          `json:"providerId"`
+	Points     []PricePoint `json:"points,omitempty"`
+	Daily      []DailyPrice `json:"daily,omitempty"`
+}
+
+type PricePoint struct {
+	RecordedAt time.Time `json:"recordedAt"`
+	Price      float32   `json:"price"`
+	Available  bool      `json:"available"`
+}
+
+// DailyPrice summarises one day of price points. Min and Max only consider
+// points where the sneaker was available; Close is the last price of the day.
+type DailyPrice struct {
+	Date      string  `json:"date"`
+	Min       float32 `json:"min"`
+	Max       float32 `json:"max"`
+	Close     float32 `json:"close"`
+	Available bool    `json:"available"`
+}
+
+// Downsample replaces Points with one DailyPrice per UTC day.
+func (h *PriceHistory) Downsample() {
+	h.Daily = nil
+
+	for _, point := range h.Points {
+		date := point.RecordedAt.UTC().Format("2006-01-02")
+
+		if len(h.Daily) == 0 || h.Daily[len(h.Daily)-1].Date != date {
+			h.Daily = append(h.Daily, DailyPrice{Date: date})
+		}
+
+		day := &h.Daily[len(h.Daily)-1]
+
+		if point.Available {
+			if day.Min == 0 || point.Price < day.Min {
+				day.Min = point.Price
+			}
+			if point.Price > day.Max {
+				day.Max = point.Price
+			}
+		}
+
+		day.Close = point.Price
+		day.Available = point.Available
+	}
+
+	h.Points = nil
+}
+
+// SearchResult is a sneaker matched by a full-text search. Lower Rank is a
+// better match.
+type SearchResult struct {
+	Id              int     `json:"id"`
+	Name            string  `json:"name"`
+	Model           string  `json:"model"`
+	Brand           string  `json:"brand"`
+	ImageUrl        string  `json:"imageUrl"`
+	Rank            float64 `json:"rank"`
+	HighlightedName string  `json:"highlightedName"`
+	Snippet         string  `json:"snippet"`
+}
+
+// Offer is an available price of a sneaker at a provider.
+type Offer struct {
+	ProviderId   int     `json:"providerId"`
+	ProviderName string  `json:"providerName"`
+	Price        float32 `json:"price"`
+}
+
+// PriceSpread summarises the prices of the available offers of a sneaker.
+type PriceSpread struct {
+	Count  int     `json:"count"`
+	Min    float32 `json:"min"`
+	Max    float32 `json:"max"`
+	Median float32 `json:"median"`
+}
+
+type SneakerOffers struct {
+	Id        int          `json:"id"`
+	Name      string       `json:"name"`
+	Model     string       `json:"model"`
+	Brand     string       `json:"brand"`
+	ImageUrl  string       `json:"imageUrl"`
+	BestOffer *Offer       `json:"bestOffer"`
+	Spread    *PriceSpread `json:"spread"`
+	Offers    []Offer      `json:"offers"`
+}
+
+// Rank sorts the offers from cheapest to most expensive and fills BestOffer
+// and Spread. Both stay nil when there are no offers.
+func (s *SneakerOffers) Rank() {
+	sort.SliceStable(s.Offers, func(i, j int) bool {
+		return s.Offers[i].Price < s.Offers[j].Price
+	})
+
+	s.BestOffer = nil
+	s.Spread = nil
+
+	count := len(s.Offers)
+	if count == 0 {
+		return
+	}
+
+	best := s.Offers[0]
+	s.BestOffer = &best
+
+	median := s.Offers[count/2].Price
+	if count%2 == 0 {
+		median = (s.Offers[count/2-1].Price + s.Offers[count/2].Price) / 2
+	}
+
+	s.Spread = &PriceSpread{
+		Count:  count,
+		Min:    s.Offers[0].Price,
+		Max:    s.Offers[count-1].Price,
+		Median: median,
+	}
 }
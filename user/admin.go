@@ -2,6 +2,6 @@ package user
 
 type Admin struct {
 	Id       int    `json:"id"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" binding:"required,email,max=254"`
+	Password string `json:"password,omitempty" binding:"required,min=8,max=72,password"`
 }
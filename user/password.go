@@ -0,0 +1,57 @@
+package user
+
+import (
+	"crypto/subtle"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"unicode"
+)
+
+// MaxPasswordBytes is the longest password bcrypt can hash. It is counted in
+// bytes, so fewer multibyte characters fit.
+const MaxPasswordBytes = 72
+
+// StrongPassword is the password policy for new passwords: at least one
+// letter and one digit, and no more than MaxPasswordBytes. Other length
+// limits are set with binding tags.
+func StrongPassword(password string) bool {
+	return len(password) <= MaxPasswordBytes &&
+		strings.IndexFunc(password, unicode.IsLetter) >= 0 && strings.IndexFunc(password, unicode.IsDigit) >= 0
+}
+
+// HashPassword returns the bcrypt hash of password for storing.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// IsHashed reports whether stored looks like a bcrypt hash rather than a
+// legacy plaintext password.
+func IsHashed(stored string) bool {
+	return strings.HasPrefix(stored, "$2a$") || strings.HasPrefix(stored, "$2b$") || strings.HasPrefix(stored, "$2y$")
+}
+
+// CheckPassword compares password against the stored value, which can be
+// either a bcrypt hash or a legacy plaintext password.
+func CheckPassword(stored string, password string) bool {
+	if IsHashed(stored) {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil
+	}
+
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+}
+
+// unknownAccountHash is a bcrypt hash at the cost of HashPassword that no
+// account uses. Checking a password against it takes as long as a real login.
+const unknownAccountHash = "$2a$10$OHHXpQ5k9eB6z.X6VqoO6OADGNElTn4pCFbY6/8JnoI3jwradHvgC"
+
+// CheckUnknownAccount spends the time of a CheckPassword call on a login for
+// an email that has no account, so response times do not reveal which emails
+// are registered.
+func CheckUnknownAccount(password string) {
+	bcrypt.CompareHashAndPassword([]byte(unknownAccountHash), []byte(password))
+}
@@ -0,0 +1,19 @@
+package user
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+func TestUnknownAccountHashMatchesHashPasswordCost(t *testing.T) {
+	hash, err := HashPassword("sneakers1")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+
+	want, _ := bcrypt.Cost([]byte(hash))
+	got, err := bcrypt.Cost([]byte(unknownAccountHash))
+	if err != nil || got != want {
+		t.Fatalf("unknown account hash cost = %d (%v), want %d so it takes as long as a real check", got, err, want)
+	}
+}
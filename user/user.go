@@ -2,7 +2,14 @@ package user
 
 type User struct {
 	Id        int    `json:"id"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
+	Email     string `json:"email" binding:"required,email,max=254"`
+	Password  string `json:"password,omitempty" binding:"required,min=8,max=72,password"`
 	CreatedAt string `json:"createdAt"`
 }
+
+// Credentials is a login request. Passwords are not checked against the
+// policy so accounts created before it can still log in.
+type Credentials struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Gretamass/kys-backend/user"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// fieldError describes one invalid field of a request body. Reason is the
+// failed rule, such as required, email or min, and Param its argument.
+type fieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+	Param  string `json:"param,omitempty"`
+}
+
+// registerValidations makes validation errors use JSON field names and adds
+// the password rule to the binding validator.
+func registerValidations() error {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("unexpected binding validator")
+	}
+
+	validate.RegisterTagNameFunc(jsonFieldName)
+
+	return validate.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		return user.StrongPassword(fl.Field().String())
+	})
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// bindJSON decodes and validates the request body. When that fails it
+// answers the request and returns false.
+func bindJSON(c *gin.Context, request interface{}) bool {
+	if err := c.ShouldBindJSON(request); err != nil {
+		respondBindError(c, err)
+		return false
+	}
+
+	return true
+}
+
+// bindPatchJSON is bindJSON for partial updates: only the fields present in
+// the body are validated, so required fields may be left out.
+func bindPatchJSON(c *gin.Context, request interface{}) bool {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "bad JSON")
+		return false
+	}
+
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(body, &present); err != nil {
+		respondError(c, http.StatusBadRequest, "bad JSON")
+		return false
+	}
+
+	if err := json.Unmarshal(body, request); err != nil {
+		respondBindError(c, err)
+		return false
+	}
+
+	var fields []string
+	requestType := reflect.TypeOf(request).Elem()
+	for i := 0; i < requestType.NumField(); i++ {
+		if _, ok := present[jsonFieldName(requestType.Field(i))]; ok {
+			fields = append(fields, requestType.Field(i).Name)
+		}
+	}
+
+	if len(fields) == 0 {
+		return true
+	}
+
+	validate := binding.Validator.Engine().(*validator.Validate)
+	if err := validate.StructPartial(request, fields...); err != nil {
+		respondBindError(c, err)
+		return false
+	}
+
+	return true
+}
+
+// respondBindError reports validation failures with 422 and one entry per
+// invalid field, and anything else as malformed JSON.
+func respondBindError(c *gin.Context, err error) {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) {
+			respondFieldErrors(c, []fieldError{{Field: typeError.Field, Reason: "type", Param: typeError.Type.String()}})
+			return
+		}
+
+		respondError(c, http.StatusBadRequest, "bad JSON")
+		return
+	}
+
+	fields := make([]fieldError, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		fields = append(fields, fieldError{
+			Field:  fieldErr.Field(),
+			Reason: fieldErr.Tag(),
+			Param:  fieldErr.Param(),
+		})
+	}
+
+	respondFieldErrors(c, fields)
+}
+
+func respondFieldErrors(c *gin.Context, fields []fieldError) {
+	body := errorBody(c, http.StatusUnprocessableEntity, "validation failed")
+	body["fields"] = fields
+
+	c.AbortWithStatusJSON(http.StatusUnprocessableEntity, body)
+}